@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetWorkspaceAnalytics returns the cumulative flow, lead and cycle times and throughput of the
+// cards of a workspace, computed from the history of their moves between lists.
+// Query parameters (all optional):
+//   - from, to: RFC3339 range, by default the 30 days up to now; at most 366 days
+//   - format: "csv" to download one report as CSV instead of JSON
+//   - report: with format=csv, "cumulative_flow", "cycle_time" (default), "throughput_weekly"
+//     or "throughput_assignee"
+func GetWorkspaceAnalytics(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	from, to, ferr := parseAnalyticsRange(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be json or csv"})
+	}
+
+	analytics, err := utils.LoadWorkspaceAnalytics(uint(workspaceID), from, to)
+	if err != nil {
+		log.Println("Error computing workspace analytics:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to compute analytics"})
+	}
+
+	if format == "json" {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"analytics": analytics})
+	}
+
+	report := c.Query("report", utils.AnalyticsReportCycleTime)
+	var buf bytes.Buffer
+	if err := utils.WriteAnalyticsCSV(&buf, analytics, report); err != nil {
+		if errors.Is(err, utils.ErrUnknownAnalyticsReport) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to export analytics"})
+	}
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="analytics-`+report+`.csv"`)
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// parseAnalyticsRange reads the optional "from" and "to" RFC3339 query parameters. to defaults
+// to now and from to utils.DefaultAnalyticsRange before to.
+func parseAnalyticsRange(c *fiber.Ctx) (time.Time, time.Time, *fiber.Error) {
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "Invalid to format")
+		}
+		to = parsed
+	}
+	from := to.Add(-utils.DefaultAnalyticsRange)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "Invalid from format")
+		}
+		from = parsed
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "to must be after from")
+	}
+	if to.Sub(from) > utils.MaxAnalyticsRange {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "The date range cannot exceed 366 days")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateAutomationRule adds a "when X then Y" rule to a workspace.
+// Expects form-data:
+//   - name and trigger (card_created, card_moved, card_completed, label_added,
+//     subtasks_completed, list_completed, deadline_passed or blocked_card_moved)
+//   - trigger_list_id (optional): only run for cards in this list
+//   - trigger_label_id (optional, label_added only): only run when this label is added
+//   - actions: JSON array of {"type": ..., "list_id"/"label_id"/"user_id": ...}, where type is
+//     complete_subtasks, complete_card, archive_card, move_to_list, add_label, assign_user or assign_owner
+//   - enabled (optional, default true)
+func CreateAutomationRule(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to create automation rule"})
+	}
+
+	rule := models.AutomationRule{
+		WorkspaceID: uint(workspaceID),
+		Enabled:     true,
+		CreatedByID: userID,
+	}
+	if ferr := applyAutomationRuleForm(c, &rule); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.CreateAutomationRule(&rule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create automation rule"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"rule": rule})
+}
+
+// GetAutomationRules returns the automation rules of a workspace.
+func GetAutomationRules(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var rules []models.AutomationRule
+	if err := repositories.GetAutomationRulesByWorkspace(uint(workspaceID), &rules); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch automation rules"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"rules": rules})
+}
+
+// UpdateAutomationRule updates an automation rule. It accepts the same form-data as
+// CreateAutomationRule; only the fields that are sent are changed, and an empty
+// trigger_list_id or trigger_label_id removes that condition.
+func UpdateAutomationRule(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	rule, ferr := loadEditableAutomationRule(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if ferr := applyAutomationRuleForm(c, rule); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.UpdateAutomationRule(rule); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update automation rule"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"rule": rule})
+}
+
+// DeleteAutomationRule deletes an automation rule and its execution logs.
+func DeleteAutomationRule(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	rule, ferr := loadEditableAutomationRule(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.DeleteAutomationRule(rule.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete automation rule"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Automation rule deleted successfully"})
+}
+
+// GetAutomationLogs returns the latest automation executions of a workspace, newest first.
+// Query parameters (all optional):
+//   - rule_id: only the executions of this rule
+//   - limit (default 50, max 200)
+func GetAutomationLogs(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	ruleID := c.QueryInt("rule_id", 0)
+	if ruleID < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid rule_id"})
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var logs []models.AutomationLog
+	if err := repositories.GetAutomationLogs(uint(workspaceID), uint(ruleID), limit, &logs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch automation logs"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"logs": logs})
+}
+
+// loadEditableAutomationRule loads the automation rule in the "id" route parameter and checks
+// that the user is an editor, admin or owner of its workspace.
+func loadEditableAutomationRule(c *fiber.Ctx, userID uint, action string) (*models.AutomationRule, *fiber.Error) {
+	ruleID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid automation rule ID")
+	}
+
+	var rule models.AutomationRule
+	if err := repositories.GetAutomationRuleByID(uint(ruleID), &rule); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Automation rule not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, rule.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" automation rule")
+	}
+	return &rule, nil
+}
+
+// applyAutomationRuleForm applies the automation rule fields present in the form to a rule and
+// validates the result.
+func applyAutomationRuleForm(c *fiber.Ctx, rule *models.AutomationRule) *fiber.Error {
+	if name, ok := lookupFormValue(c, "name"); ok {
+		rule.Name = strings.TrimSpace(name)
+	}
+	if rule.Name == "" || len(rule.Name) > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "name is required and must be at most 100 characters")
+	}
+
+	if trigger, ok := lookupFormValue(c, "trigger"); ok {
+		rule.Trigger = trigger
+	}
+
+	var err error
+	if raw, ok := lookupFormValue(c, "trigger_list_id"); ok {
+		if rule.TriggerListID, err = parseOptionalID(raw); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid trigger_list_id")
+		}
+	}
+	if raw, ok := lookupFormValue(c, "trigger_label_id"); ok {
+		if rule.TriggerLabelID, err = parseOptionalID(raw); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid trigger_label_id")
+		}
+	}
+
+	if raw, ok := lookupFormValue(c, "actions"); ok {
+		var actions models.AutomationActions
+		if err := json.Unmarshal([]byte(raw), &actions); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "actions must be a JSON array of actions")
+		}
+		rule.Actions = actions
+	}
+
+	if raw, ok := lookupFormValue(c, "enabled"); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid enabled value")
+		}
+		rule.Enabled = enabled
+	}
+
+	if err := utils.ValidateAutomationRule(rule); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// parseOptionalID parses an ID. An empty string yields nil.
+func parseOptionalID(raw string) (*uint, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	value := uint(id)
+	return &value, nil
+}
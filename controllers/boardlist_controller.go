@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"kelarin-backend/utils"
 	"log"
 	"strconv"
@@ -12,7 +13,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateBoardList creates a new board list within a workspace.
+// CreateBoardList creates a new board list within a workspace. An optional "wip_limit" caps the
+// number of cards in the list (0 for no limit), enforced as set by "wip_limit_mode": "soft"
+// (default) warns when the list goes over it and "hard" refuses more cards.
 func CreateBoardList(c *fiber.Ctx) error {
 	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
 	if err != nil {
@@ -38,25 +41,31 @@ func CreateBoardList(c *fiber.Ctx) error {
 	}
 
 	list := models.BoardList{
-		Title:       title,
-		WorkspaceID: uint(workspaceID),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Title:        title,
+		WorkspaceID:  uint(workspaceID),
+		WIPLimitMode: models.WIPLimitSoft,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if _, err := applyWIPLimit(c, &list); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	if err := repositories.CreateBoardList(&list); err != nil {
 		log.Println("Error creating board list:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create board list"})
 	}
+	undoToken := recordUndo(userID, utils.UndoEntityList, utils.UndoActionCreate, utils.UndoKey{ID: list.ID}, "")
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list, "undo_token": undoToken})
 }
 
-// GetBoardLists returns all board lists for a given workspace.
+// GetBoardLists returns all board lists for a given workspace. Archived lists and cards are
+// only included with the "include_archived=true" query parameter.
 func GetBoardLists(c *fiber.Ctx) error {
 	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
 	if err != nil {
@@ -64,53 +73,201 @@ func GetBoardLists(c *fiber.Ctx) error {
 	}
 
 	var lists []models.BoardList
-	if err := repositories.GetBoardListsByWorkspace(uint(workspaceID), &lists); err != nil {
+	if err := repositories.GetBoardListsByWorkspace(uint(workspaceID), c.QueryBool("include_archived", false), &lists); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch board lists"})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"lists": lists})
 }
 
-// UpdateBoardList updates an existing board list.
+// UpdateBoardList updates the title, wip_limit or wip_limit_mode of an existing board list.
+// Only the fields that are sent are changed; lowering the WIP limit below the number of cards
+// already in the list does not move any of them out.
+// Send the list's ETag in If-Match to reject the update with 409 Conflict if the list has been
+// changed since it was loaded.
 func UpdateBoardList(c *fiber.Ctx) error {
-	listID, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list ID"})
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	var list models.BoardList
-	if err := repositories.GetBoardListByID(uint(listID), &list); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "List not found"})
+	loaded, ferr := loadEditableBoardList(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	list := *loaded
+	if !utils.IfMatchSatisfied(c.Get(fiber.HeaderIfMatch), list.Version) {
+		return respondVersionConflict(c, "list", list, list.Version)
 	}
+	before := captureUndo(utils.UndoEntityList, utils.UndoKey{ID: list.ID})
 
-	list.Title = c.FormValue("title")
-	list.UpdatedAt = time.Now()
+	var columns []string
+	if title, ok := lookupFormValue(c, "title"); ok {
+		list.Title = title
+		columns = append(columns, "title")
+	}
+	wipColumns, err := applyWIPLimit(c, &list)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	columns = append(columns, wipColumns...)
+
+	if len(columns) == 0 {
+		c.Set(fiber.HeaderETag, utils.ETag(list.Version))
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list})
+	}
 
-	if err := repositories.UpdateBoardList(&list); err != nil {
+	updated, err := repositories.UpdateBoardList(&list, columns)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update board list"})
 	}
+	if !updated {
+		var current models.BoardList
+		if err := repositories.GetBoardListByID(list.ID, &current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "List not found"})
+		}
+		return respondVersionConflict(c, "list", current, current.Version)
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityList, utils.UndoActionUpdate, utils.UndoKey{ID: list.ID}, before)
 
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	c.Set(fiber.HeaderETag, utils.ETag(list.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list, "undo_token": undoToken})
+}
+
+// DeleteBoardList deletes a board list.
+func DeleteBoardList(c *fiber.Ctx) error {
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	list, ferr := loadEditableBoardList(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	before := captureUndo(utils.UndoEntityList, utils.UndoKey{ID: list.ID})
+	if err := repositories.DeleteBoardList(list.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete board list"})
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityList, utils.UndoActionDelete, utils.UndoKey{ID: list.ID}, before)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Board list deleted successfully", "undo_token": undoToken})
 }
 
-// DeleteBoardList deletes a board list.
-func DeleteBoardList(c *fiber.Ctx) error {
+// CompleteBoardList marks a board list as completed.
+func CompleteBoardList(c *fiber.Ctx) error {
+	return setBoardListState(c, "complete")
+}
+
+// ReopenBoardList clears the completion of a board list.
+func ReopenBoardList(c *fiber.Ctx) error {
+	return setBoardListState(c, "reopen")
+}
+
+// ArchiveBoardList archives a board list, hiding it and its cards from the board.
+func ArchiveBoardList(c *fiber.Ctx) error {
+	return setBoardListState(c, "archive")
+}
+
+// UnarchiveBoardList brings an archived board list back to the board.
+func UnarchiveBoardList(c *fiber.Ctx) error {
+	return setBoardListState(c, "unarchive")
+}
+
+// setBoardListState applies a complete, reopen, archive or unarchive action to the board list
+// in the "id" route parameter.
+func setBoardListState(c *fiber.Ctx, action string) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	list, ferr := loadEditableBoardList(c, userID, action)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var err error
+	before := captureUndo(utils.UndoEntityList, utils.UndoKey{ID: list.ID})
+	now := time.Now()
+	switch action {
+	case "complete", "reopen":
+		if (action == "complete") == (list.CompletedAt != nil) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Board list is already in that state"})
+		}
+		if action == "complete" {
+			list.CompletedAt = &now
+		} else {
+			list.CompletedAt = nil
+		}
+		err = repositories.SetBoardListCompletedAt(list.ID, list.CompletedAt)
+	case "archive", "unarchive":
+		if (action == "archive") == (list.ArchivedAt != nil) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Board list is already in that state"})
+		}
+		if action == "archive" {
+			list.ArchivedAt = &now
+		} else {
+			list.ArchivedAt = nil
+		}
+		err = repositories.SetBoardListArchivedAt(list.ID, list.ArchivedAt)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to " + action + " board list"})
+	}
+	list.UpdatedAt = now
+	list.Version++
+	undoToken := recordUndo(userID, utils.UndoEntityList, utils.UndoActionUpdate, utils.UndoKey{ID: list.ID}, before)
+	if action == "complete" {
+		utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerListCompleted, ListID: list.ID, ActorID: userID})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": list, "undo_token": undoToken})
+}
+
+// loadEditableBoardList loads the board list in the "id" route parameter and checks that the
+// user may edit lists in its workspace. action completes the "Insufficient permission to ...
+// board list" error.
+func loadEditableBoardList(c *fiber.Ctx, userID uint, action string) (*models.BoardList, *fiber.Error) {
 	listID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list ID"})
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid list ID")
 	}
 
-	if err := repositories.DeleteBoardList(uint(listID)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete board list"})
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(uint(listID), &list); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "List not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, list.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" board list")
+	}
+	return &list, nil
+}
+
+// GetArchivedItems returns the archived lists of a workspace with their cards, and the
+// archived cards that are still in active lists.
+func GetArchivedItems(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
 	}
 
 	userID, ok := c.Locals("user_id").(uint)
@@ -118,9 +275,41 @@ func DeleteBoardList(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	if err := utils.IncrementStreak(userID); err != nil {
-		log.Println("Error incrementing streak:", err)
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var lists []models.BoardList
+	if err := repositories.GetArchivedBoardListsByWorkspace(uint(workspaceID), &lists); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch archived lists"})
+	}
+
+	var cards []models.Card
+	if err := repositories.GetArchivedCardsByWorkspace(uint(workspaceID), &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch archived cards"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Board list deleted successfully"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"lists": lists, "cards": cards})
+}
+
+// applyWIPLimit applies the optional "wip_limit" and "wip_limit_mode" form values to a board
+// list, returning the columns it changed.
+func applyWIPLimit(c *fiber.Ctx, list *models.BoardList) ([]string, error) {
+	var columns []string
+	if raw, ok := lookupFormValue(c, "wip_limit"); ok {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return nil, errors.New("wip_limit must be a number of cards, or 0 for no limit")
+		}
+		list.WIPLimit = limit
+		columns = append(columns, "wip_limit")
+	}
+	if raw, ok := lookupFormValue(c, "wip_limit_mode"); ok {
+		if !utils.IsValidWIPLimitMode(raw) {
+			return nil, errors.New("wip_limit_mode must be hard or soft")
+		}
+		list.WIPLimitMode = raw
+		columns = append(columns, "wip_limit_mode")
+	}
+	return columns, nil
 }
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"kelarin-backend/dto"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// maxCalendarRange is the longest date range a calendar request may cover.
+const maxCalendarRange = 366 * 24 * time.Hour
+
+// Calendar feeds include deadlines from this far in the past up to this far in the future.
+const (
+	calendarFeedPast   = 90 * 24 * time.Hour
+	calendarFeedFuture = 365 * 24 * time.Hour
+)
+
+// GetWorkspaceCalendar returns the cards of a workspace with a deadline in the range given by
+// the "from" and "to" RFC3339 query parameters.
+func GetWorkspaceCalendar(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	from, to, ferr := parseCalendarRange(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var cards []models.Card
+	if err := repositories.GetCardsByWorkspaceDeadlineRange(uint(workspaceID), from, to, &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch calendar"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": newCalendarResponse(cards)})
+}
+
+// GetMyCalendar returns the cards assigned to the authenticated user with a deadline in the range
+// given by the "from" and "to" RFC3339 query parameters.
+func GetMyCalendar(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	from, to, ferr := parseCalendarRange(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var cards []models.Card
+	if err := repositories.GetAssignedCardsByDeadlineRange(userID, from, to, &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch calendar"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": newCalendarResponse(cards)})
+}
+
+// GetMyCalendarFeed returns the iCalendar subscription URL of the authenticated user's assigned cards,
+// creating it on first use.
+func GetMyCalendarFeed(c *fiber.Ctx) error {
+	return calendarFeed(c, nil, false)
+}
+
+// RegenerateMyCalendarFeed replaces the token of the authenticated user's personal calendar feed,
+// invalidating the previous subscription URL.
+func RegenerateMyCalendarFeed(c *fiber.Ctx) error {
+	return calendarFeed(c, nil, true)
+}
+
+// GetWorkspaceCalendarFeed returns the authenticated user's iCalendar subscription URL for a workspace,
+// creating it on first use.
+func GetWorkspaceCalendarFeed(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+	id := uint(workspaceID)
+	return calendarFeed(c, &id, false)
+}
+
+// RegenerateWorkspaceCalendarFeed replaces the token of the authenticated user's workspace calendar feed,
+// invalidating the previous subscription URL.
+func RegenerateWorkspaceCalendarFeed(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+	id := uint(workspaceID)
+	return calendarFeed(c, &id, true)
+}
+
+// ServeCalendarFeed serves an iCalendar feed identified by its secret token. It requires no
+// authentication so calendar applications can subscribe to it.
+func ServeCalendarFeed(c *fiber.Ctx) error {
+	var token models.CalendarToken
+	if err := repositories.GetCalendarTokenByToken(c.Params("token"), &token); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar not found"})
+	}
+
+	now := time.Now()
+	from, to := now.Add(-calendarFeedPast), now.Add(calendarFeedFuture)
+
+	var cards []models.Card
+	name := "KelarIn - My cards"
+	if token.WorkspaceID != nil {
+		// The feed stops working once its owner loses access to the workspace.
+		if _, err := utils.CheckRoleInWorkspace(token.UserID, *token.WorkspaceID); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar not found"})
+		}
+
+		var ws models.Workspace
+		if err := repositories.GetWorkspaceByIDWithOwner(strconv.Itoa(int(*token.WorkspaceID)), &ws); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Calendar not found"})
+		}
+		name = "KelarIn - " + ws.Title
+
+		if err := repositories.GetCardsByWorkspaceDeadlineRange(ws.ID, from, to, &cards); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch calendar"})
+		}
+	} else {
+		if err := repositories.GetAssignedCardsByDeadlineRange(token.UserID, from, to, &cards); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch calendar"})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="kelarin.ics"`)
+	return c.Status(fiber.StatusOK).SendString(utils.BuildICalendar(name, cards, now))
+}
+
+// calendarFeed returns the subscription URL of a calendar feed of the authenticated user,
+// creating its token if it does not exist yet or if regenerate is set.
+func calendarFeed(c *fiber.Ctx, workspaceID *uint, regenerate bool) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if workspaceID != nil {
+		if _, err := utils.CheckRoleInWorkspace(userID, *workspaceID); err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+		}
+	}
+
+	var token models.CalendarToken
+	err := repositories.GetCalendarToken(userID, workspaceID, &token)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch calendar feed"})
+	}
+
+	if err != nil || regenerate {
+		value, err := utils.GenerateCalendarToken()
+		if err != nil {
+			log.Println("Error generating calendar token:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate calendar feed"})
+		}
+
+		token = models.CalendarToken{
+			Token:       value,
+			UserID:      userID,
+			WorkspaceID: workspaceID,
+			CreatedAt:   time.Now(),
+		}
+		if err := repositories.ReplaceCalendarToken(&token); err != nil {
+			log.Println("Error saving calendar token:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate calendar feed"})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"url":        c.BaseURL() + "/api/calendar/" + token.Token + ".ics",
+		"created_at": token.CreatedAt,
+	})
+}
+
+// parseCalendarRange reads the required "from" and "to" RFC3339 query parameters.
+func parseCalendarRange(c *fiber.Ctx) (time.Time, time.Time, *fiber.Error) {
+	from, err := time.Parse(time.RFC3339, c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "from must be an RFC3339 date-time")
+	}
+	to, err := time.Parse(time.RFC3339, c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "to must be an RFC3339 date-time")
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "to must be after from")
+	}
+	if to.Sub(from) > maxCalendarRange {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "The date range cannot exceed 366 days")
+	}
+	return from, to, nil
+}
+
+// newCalendarResponse converts cards into summaries with their deadline bucket.
+func newCalendarResponse(cards []models.Card) []dto.CardSummaryResponse {
+	now := time.Now()
+	response := make([]dto.CardSummaryResponse, len(cards))
+	for i, card := range cards {
+		response[i] = dto.NewCardSummaryResponse(&card, utils.DeadlineBucket(card.Deadline, now))
+	}
+	return response
+}
@@ -12,7 +12,8 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateAssignee adds a user as an assignee to a card.
+// CreateAssignee adds a user as an assignee to a card. The user must be a member of the
+// card's workspace.
 func CreateAssignee(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("card_id"))
 	if err != nil {
@@ -28,6 +29,19 @@ func CreateAssignee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user_id"})
 	}
 
+	actorID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadEditableCardByID(uint(cardID), actorID, "assign users to")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if _, err := utils.CheckRoleInWorkspace(uint(userID), card.List.WorkspaceID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User is not a member of this workspace"})
+	}
+
 	assignee := models.CardAssignee{
 		CardID: uint(cardID),
 		UserID: uint(userID),
@@ -46,8 +60,13 @@ func CreateAssignee(c *fiber.Ctx) error {
 		log.Println("Error incrementing streak:", err)
 	}
 
+	undoToken := recordUndo(actorID, utils.UndoEntityCardAssignee, utils.UndoActionCreate, utils.UndoKey{CardID: uint(cardID), UserID: uint(userID)}, "")
+	if err := utils.NotifyCardAssigned(card, uint(userID), actorID); err != nil {
+		log.Println("Error notifying assignee:", err)
+	}
+
 	response := dto.NewCardAssigneeResponse(&populatedAssignee)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"assignee": response})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"assignee": response, "undo_token": undoToken})
 }
 
 // GetAssignees retrieves all assignees for a given card.
@@ -101,13 +120,26 @@ func DeleteAssignee(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user_id"})
 	}
 
+	actorID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, ferr := loadEditableCardByID(uint(cardID), actorID, "unassign users from"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	key := utils.UndoKey{CardID: uint(cardID), UserID: uint(userID)}
+	before := captureUndo(utils.UndoEntityCardAssignee, key)
 	if err := repositories.DeleteCardAssignee(uint(cardID), uint(userID)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to remove assignee"})
 	}
 
+	undoToken := recordUndo(actorID, utils.UndoEntityCardAssignee, utils.UndoActionDelete, key, before)
+
 	if err := utils.IncrementStreak(uint(userID)); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Assignee removed successfully"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Assignee removed successfully", "undo_token": undoToken})
 }
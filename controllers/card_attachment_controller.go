@@ -3,6 +3,7 @@ package controllers
 import (
 	"kelarin-backend/utils"
 	"log"
+	"os"
 	"strconv"
 	"time"
 
@@ -62,6 +63,37 @@ func GetAttachments(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"attachments": attachments})
 }
 
+// DownloadInboundAttachment serves a file attached to a card by an inbound email. The user
+// must be a member of the card's workspace.
+func DownloadInboundAttachment(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_id"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(uint(cardID), &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	if _, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	path, ok := utils.InboundAttachmentPath(card.ID, c.Params("name"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment not found"})
+	}
+	if _, err := os.Stat(path); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment not found"})
+	}
+	return c.Download(path)
+}
+
 // GetCardAttachment retrieves a card attachment by its ID.
 func GetCardAttachment(c *fiber.Ctx) error {
 	attachmentID, err := strconv.Atoi(c.Params("id"))
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BulkUpdateCards applies one operation to a set of cards in a single transaction and reports
+// the outcome for each card. Cards the user may not change are skipped and reported as failed.
+// Expects form-data:
+//   - operation: "move", "assign", "unassign", "label", "set_deadline", "archive" or "delete"
+//   - card_ids: comma-separated card IDs
+//   - list_id for move, user_id for assign and unassign, label_id for label
+//   - deadline (RFC3339) for set_deadline; leave it empty to clear the deadline
+func BulkUpdateCards(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	op := c.FormValue("operation")
+	if !repositories.IsValidBulkCardOperation(op) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "operation must be move, assign, unassign, label, set_deadline, archive or delete"})
+	}
+
+	cardIDs, err := parseIDList(c.FormValue("card_ids"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_ids"})
+	}
+	if len(cardIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "card_ids is required"})
+	}
+	if len(cardIDs) > utils.MaxBulkCards {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Too many cards in one request"})
+	}
+
+	var change repositories.BulkCardChange
+	switch op {
+	case repositories.BulkCardMove:
+		id, err := strconv.Atoi(c.FormValue("list_id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "list_id is required"})
+		}
+		change.ListID = uint(id)
+	case repositories.BulkCardAssign, repositories.BulkCardUnassign:
+		id, err := strconv.Atoi(c.FormValue("user_id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "user_id is required"})
+		}
+		change.UserID = uint(id)
+	case repositories.BulkCardLabel:
+		id, err := strconv.Atoi(c.FormValue("label_id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "label_id is required"})
+		}
+		change.LabelID = uint(id)
+	case repositories.BulkCardSetDeadline:
+		if change.Deadline, err = parseOptionalTime(c.FormValue("deadline")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid deadline format"})
+		}
+	}
+
+	results, err := utils.ApplyBulkCardOperation(userID, op, cardIDs, change)
+	if err != nil {
+		if errors.Is(err, utils.ErrBulkTargetNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Target list or label not found"})
+		}
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Println("Error applying bulk card operation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update cards"})
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	if succeeded > 0 {
+		if err := utils.IncrementStreak(userID); err != nil {
+			log.Println("Error incrementing streak:", err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
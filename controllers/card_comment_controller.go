@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"kelarin-backend/utils"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"kelarin-backend/dto"
@@ -11,6 +13,7 @@ import (
 	"kelarin-backend/repositories"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // CreateCardComment adds a comment to a card.
@@ -26,10 +29,17 @@ func CreateCardComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	if _, ferr := checkCardCommentAccess(uint(cardID), userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
 	commentText := c.FormValue("comment")
 	if commentText == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Comment cannot be empty"})
 	}
+	if err := utils.ValidateLength("Comment", commentText, utils.MaxCommentLength); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	comment := models.CardComment{
 		CardID:    uint(cardID),
@@ -38,11 +48,38 @@ func CreateCardComment(c *fiber.Ctx) error {
 		CreatedAt: time.Now(),
 	}
 
+	// Optional parent_id makes the comment a reply. Replies to a reply join the parent's thread.
+	if parentIDStr := c.FormValue("parent_id"); parentIDStr != "" {
+		parentID, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid parent_id"})
+		}
+
+		var parent models.CardComment
+		if err := repositories.GetCardCommentByID(uint(parentID), &parent); err != nil || parent.CardID != uint(cardID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Parent comment not found on this card"})
+		}
+		if parent.DeletedAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot reply to a deleted comment"})
+		}
+
+		threadID := parent.ID
+		if parent.ParentID != nil {
+			threadID = *parent.ParentID
+		}
+		comment.ParentID = &threadID
+	}
+
 	if err := repositories.CreateCardComment(&comment); err != nil {
 		log.Println("Error creating card comment:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card comment"})
 	}
 
+	card, mentionedIDs, err := saveCommentMentions(&comment)
+	if err != nil {
+		log.Println("Error saving comment mentions:", err)
+	}
+
 	var populatedComment models.CardComment
 	if err := repositories.GetCardCommentByID(comment.ID, &populatedComment); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to preload comment data"})
@@ -52,27 +89,41 @@ func CreateCardComment(c *fiber.Ctx) error {
 		log.Println("Error incrementing streak:", err)
 	}
 
+	notifyMentionedUsers(card, mentionedIDs, userID)
+
 	response := dto.NewCardCommentResponse(&populatedComment)
+	if card != nil {
+		utils.EmitWebhookEvent(card.List.WorkspaceID, models.WebhookEventCommentAdded, map[string]interface{}{
+			"card_id":    comment.CardID,
+			"card_title": card.Title,
+			"comment":    response,
+		})
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"comment": response})
 }
 
-// GetComments retrieves all comments for a given card.
+// GetComments retrieves all comments for a given card, grouped into reply threads.
 func GetComments(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("card_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_id"})
 	}
 
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, ferr := checkCardCommentAccess(uint(cardID), userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
 	var comments []models.CardComment
 	if err := repositories.GetCommentsByCardID(uint(cardID), &comments); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch comments"})
 	}
 
-	var response []dto.CardCommentResponse
-	for _, comment := range comments {
-		response = append(response, dto.NewCardCommentResponse(&comment))
-	}
-
+	response := dto.NewCardCommentThreads(comments)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"comments": response})
 }
 
@@ -83,16 +134,24 @@ func GetCardComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
 	}
 
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
 	var comment models.CardComment
 	if err := repositories.GetCardCommentByID(uint(commentID), &comment); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
 	}
+	if _, ferr := checkCommentAccess(&comment, userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
 
 	response := dto.NewCardCommentResponse(&comment)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"comment": response})
 }
 
-// UpdateCardComment updates a card comment.
+// UpdateCardComment edits a card comment, keeping its previous text as a revision.
 func UpdateCardComment(c *fiber.Ctx) error {
 	commentID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -104,20 +163,56 @@ func UpdateCardComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
 	}
 
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, ferr := checkCommentAccess(&comment, userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if comment.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only edit your own comments"})
+	}
+
+	if comment.DeletedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot edit a deleted comment"})
+	}
+
 	newComment := c.FormValue("comment")
-	if newComment != "" {
+	if err := utils.ValidateLength("Comment", newComment, utils.MaxCommentLength); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if newComment != "" && newComment != comment.Comment {
+		revision := models.CommentRevision{
+			CommentID: comment.ID,
+			Comment:   comment.Comment,
+			EditedBy:  userID,
+			CreatedAt: time.Now(),
+		}
+
+		editedAt := time.Now()
 		comment.Comment = newComment
-		comment.CreatedAt = time.Now() // Optional: update timestamp
+		comment.EditedAt = &editedAt
+
+		if err := repositories.UpdateCardCommentWithRevision(&comment, &revision); err != nil {
+			log.Println("Error updating card comment:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment"})
+		}
 	}
 
-	if err := repositories.UpdateCardComment(&comment); err != nil {
-		log.Println("Error updating card comment:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update comment"})
+	previousIDs, err := repositories.GetMentionedUserIDsByComment(comment.ID)
+	if err != nil {
+		log.Println("Error loading previous comment mentions:", err)
+	}
+	card, mentionedIDs, err := saveCommentMentions(&comment)
+	if err != nil {
+		log.Println("Error saving comment mentions:", err)
 	}
+	notifyMentionedUsers(card, newlyMentioned(previousIDs, mentionedIDs), userID)
 
-	userID, ok := c.Locals("user_id").(uint)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	if err := repositories.GetCardCommentByID(comment.ID, &comment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to preload comment data"})
 	}
 
 	if err := utils.IncrementStreak(userID); err != nil {
@@ -128,24 +223,197 @@ func UpdateCardComment(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"comment": response})
 }
 
-// DeleteCardComment deletes a card comment by its ID.
+// DeleteCardComment deletes a card comment by its ID. Only its author or a workspace admin
+// or owner may delete it. The comment stays in its thread as a "comment deleted" placeholder.
 func DeleteCardComment(c *fiber.Ctx) error {
 	commentID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
 	}
-	if err := repositories.DeleteCardComment(uint(commentID)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete comment"})
-	}
 
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	var comment models.CardComment
+	if err := repositories.GetCardCommentByID(uint(commentID), &comment); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	role, ferr := checkCommentAccess(&comment, userID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if comment.UserID != userID && !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to delete comment"})
+	}
+
+	if err := repositories.DeleteCardComment(comment.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete comment"})
+	}
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Comment deleted successfully"})
 }
+
+// GetCommentRevisions returns the previous versions of a comment, newest first.
+func GetCommentRevisions(c *fiber.Ctx) error {
+	commentID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var comment models.CardComment
+	if err := repositories.GetCardCommentByID(uint(commentID), &comment); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	if _, ferr := checkCommentAccess(&comment, userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var revisions []models.CommentRevision
+	if err := repositories.GetCommentRevisions(comment.ID, &revisions); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch comment revisions"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"revisions": revisions})
+}
+
+// ToggleCommentReaction adds the authenticated user's emoji reaction to a comment,
+// or removes it if the user already reacted with that emoji.
+func ToggleCommentReaction(c *fiber.Ctx) error {
+	commentID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid comment ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	emoji := strings.TrimSpace(c.FormValue("emoji"))
+	if emoji == "" || len(emoji) > 32 || strings.ContainsAny(emoji, " \t\n") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Emoji must be a single emoji or shortcode"})
+	}
+
+	var comment models.CardComment
+	if err := repositories.GetCardCommentByID(uint(commentID), &comment); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Comment not found"})
+	}
+	if _, ferr := checkCommentAccess(&comment, userID); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if comment.DeletedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot react to a deleted comment"})
+	}
+
+	reaction := models.CommentReaction{
+		CommentID: comment.ID,
+		UserID:    userID,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	}
+	added, err := repositories.ToggleCommentReaction(&reaction)
+	if err != nil {
+		log.Println("Error toggling comment reaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to toggle reaction"})
+	}
+
+	if err := repositories.GetCardCommentByID(comment.ID, &comment); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to preload comment data"})
+	}
+
+	response := dto.NewCardCommentResponse(&comment)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"added": added, "comment": response})
+}
+
+// checkCommentAccess returns the user's role in the workspace the comment's card belongs to,
+// or a *fiber.Error if the user is not a member of it.
+func checkCommentAccess(comment *models.CardComment, userID uint) (string, *fiber.Error) {
+	return checkCardCommentAccess(comment.CardID, userID)
+}
+
+// checkCardCommentAccess returns the user's role in the workspace the card belongs to, or a
+// *fiber.Error if the user is not a member of it.
+func checkCardCommentAccess(cardID, userID uint) (string, *fiber.Error) {
+	var card models.Card
+	if err := repositories.GetCardWithListByID(cardID, &card); err != nil {
+		return "", fiber.NewError(fiber.StatusNotFound, "Card not found")
+	}
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusForbidden, "You do not have access to this workspace")
+	}
+	return role, nil
+}
+
+// saveCommentMentions parses the @mentions of a comment, replaces its stored mentions,
+// and returns the comment's card along with the IDs of the mentioned users.
+func saveCommentMentions(comment *models.CardComment) (*models.Card, []uint, error) {
+	var card models.Card
+	if err := repositories.GetCardWithListByID(comment.CardID, &card); err != nil {
+		return nil, nil, err
+	}
+
+	spans, err := utils.ParseMentions(card.List.WorkspaceID, comment.Comment)
+	if err != nil {
+		return &card, nil, err
+	}
+
+	mentions := make([]models.CommentMention, len(spans))
+	for i, span := range spans {
+		mentions[i] = models.CommentMention{
+			CommentID: comment.ID,
+			UserID:    span.User.ID,
+			Handle:    span.Handle,
+			Start:     span.Start,
+			End:       span.End,
+		}
+	}
+	if err := repositories.ReplaceCommentMentions(comment.ID, mentions); err != nil {
+		return &card, nil, err
+	}
+
+	return &card, utils.MentionedUserIDs(spans), nil
+}
+
+// newlyMentioned returns the IDs in current that are not in previous.
+func newlyMentioned(previous, current []uint) []uint {
+	seen := make(map[uint]bool, len(previous))
+	for _, id := range previous {
+		seen[id] = true
+	}
+
+	var ids []uint
+	for _, id := range current {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// notifyMentionedUsers notifies the given users that they were mentioned in a comment on the card.
+func notifyMentionedUsers(card *models.Card, userIDs []uint, actorID uint) {
+	if card == nil {
+		return
+	}
+
+	for _, id := range userIDs {
+		if err := utils.NotifyCommentMention(card, id, actorID); err != nil {
+			log.Println("Error notifying mentioned user:", err)
+		}
+	}
+}
@@ -1,18 +1,22 @@
 package controllers
 
 import (
+	"errors"
 	"kelarin-backend/utils"
 	"log"
 	"strconv"
 	"time"
 
+	"kelarin-backend/dto"
 	"kelarin-backend/models"
 	"kelarin-backend/repositories"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateCard creates a new card in a specific board list.
+// CreateCard creates a new card in a specific board list, with an optional "priority" (none,
+// low, medium, high or urgent). A list at its hard WIP limit rejects the card with 409 Conflict;
+// over a soft limit the card is created with a "warning".
 func CreateCard(c *fiber.Ctx) error {
 	listID, err := strconv.Atoi(c.Params("list_id"))
 	if err != nil {
@@ -41,6 +45,10 @@ func CreateCard(c *fiber.Ctx) error {
 	description := c.FormValue("description")
 	deadlineStr := c.FormValue("deadline") // optional deadline field
 
+	if err := validateCardText(title, description); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	var deadline *time.Time
 	if deadlineStr != "" {
 		parsed, err := time.Parse(time.RFC3339, deadlineStr)
@@ -58,20 +66,44 @@ func CreateCard(c *fiber.Ctx) error {
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if _, err := applyCardSchedule(c, &card); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := applyCardPriority(c, &card); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	warning, ferr := checkListWIPLimit(card.ListID, 1)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
 
 	if err := repositories.CreateCard(&card); err != nil {
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
 		log.Println("Error creating card:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card"})
 	}
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionCreate, utils.UndoKey{ID: card.ID}, "")
+	utils.EmitCardWebhookEvent(models.WebhookEventCardCreated, &card)
+	utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerCardCreated, CardID: card.ID, ListID: card.ListID, ActorID: userID})
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card})
+	response := fiber.Map{"card": card, "undo_token": undoToken}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
 }
 
-// GetCards retrieves all cards for a given list.
+// GetCards retrieves all cards for a given list, with the completion percentage of their
+// subtasks. Archived cards are only included with the "include_archived=true" query parameter.
+// Cards can be filtered by custom fields with the "cf.*" query parameters and sorted with
+// "sort" and "order", as in SearchCards.
 func GetCards(c *fiber.Ctx) error {
 	listID, err := strconv.Atoi(c.Params("list_id"))
 	if err != nil {
@@ -79,14 +111,20 @@ func GetCards(c *fiber.Ctx) error {
 	}
 
 	var cards []models.Card
-	if err := repositories.GetCardsByListID(uint(listID), &cards); err != nil {
+	if hasCardQuery(c) {
+		if ferr := queryListCards(c, uint(listID), &cards); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+	} else if err := repositories.GetCardsByListID(uint(listID), c.QueryBool("include_archived", false), &cards); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch cards"})
 	}
+	utils.SetCompletionPercents(cards)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": cards})
 }
 
-// GetCard returns a card by its ID including attachments, labels, and comments.
+// GetCard returns a card by its ID including attachments, labels, and comments, with its links
+// to other cards and whether it is blocked by an open card.
 func GetCard(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -98,68 +136,482 @@ func GetCard(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card})
+	links, ferr := loadCardLinks(card.ID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	c.Set(fiber.HeaderETag, utils.ETag(card.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card, "links": links, "blocked": dto.IsBlocked(links)})
 }
 
-// UpdateCard updates a card's basic fields.
+// UpdateCard partially updates a card: only the form-data fields that are sent are changed,
+// and an empty deadline or start_date clears it. An optional "list_id" moves the card to another
+// list of the same workspace. A list at its hard WIP limit rejects the move with 409 Conflict;
+// moving a card over a soft limit, or with open blockers to a completed list, succeeds with a
+// "warning". Send the card's ETag in If-Match to reject the update with 409 Conflict if
+// someone else has changed the card since it was loaded.
 func UpdateCard(c *fiber.Ctx) error {
-	cardID, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	var card models.Card
-	if err := repositories.GetCardByID(uint(cardID), &card); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	loaded, ferr := loadEditableCard(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	card := *loaded
+	if !utils.IfMatchSatisfied(c.Get(fiber.HeaderIfMatch), card.Version) {
+		return respondVersionConflict(c, "card", card, card.Version)
 	}
+	before := captureUndo(utils.UndoEntityCard, utils.UndoKey{ID: card.ID})
 
-	card.Title = c.FormValue("title")
-	card.Description = c.FormValue("description")
-	deadlineStr := c.FormValue("deadline")
-	if deadlineStr != "" {
-		parsed, err := time.Parse(time.RFC3339, deadlineStr)
+	var columns []string
+	fromListID := card.ListID
+	moved := false
+	if raw, ok := lookupFormValue(c, "list_id"); ok {
+		listID, err := strconv.Atoi(raw)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid deadline format"})
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list_id"})
 		}
-		card.Deadline = &parsed
+		if uint(listID) != card.ListID {
+			fromWorkspaceID, err := repositories.GetWorkspaceIDByListID(card.ListID)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to retrieve workspace from list"})
+			}
+			toWorkspaceID, err := repositories.GetWorkspaceIDByListID(uint(listID))
+			if err != nil || toWorkspaceID != fromWorkspaceID {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "list_id must be a list in the same workspace"})
+			}
+			card.ListID = uint(listID)
+			columns = append(columns, "list_id")
+			moved = true
+		}
+	}
+
+	if title, ok := lookupFormValue(c, "title"); ok {
+		card.Title = title
+		columns = append(columns, "title")
+	}
+	if description, ok := lookupFormValue(c, "description"); ok {
+		card.Description = description
+		columns = append(columns, "description")
+	}
+	if err := validateCardText(card.Title, card.Description); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if raw, ok := lookupFormValue(c, "deadline"); ok {
+		card.Deadline = nil
+		if raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid deadline format"})
+			}
+			card.Deadline = &parsed
+		}
+		columns = append(columns, "deadline")
+	}
+	scheduleColumns, err := applyCardSchedule(c, &card)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
-	card.UpdatedAt = time.Now()
+	columns = append(columns, scheduleColumns...)
+	priorityColumns, err := applyCardPriority(c, &card)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	columns = append(columns, priorityColumns...)
 
-	if err := repositories.UpdateCard(&card); err != nil {
+	wipWarning := ""
+	if moved {
+		var ferr *fiber.Error
+		if wipWarning, ferr = checkListWIPLimit(card.ListID, 1); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+	}
+
+	if len(columns) == 0 {
+		c.Set(fiber.HeaderETag, utils.ETag(card.Version))
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card})
+	}
+
+	updated, err := repositories.UpdateCard(&card, columns)
+	if err != nil {
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update card"})
 	}
+	if !updated {
+		var current models.Card
+		if err := repositories.GetCardByID(card.ID, &current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+		}
+		return respondVersionConflict(c, "card", current, current.Version)
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionUpdate, utils.UndoKey{ID: card.ID}, before)
+	if !moved || len(columns) > 1 {
+		utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, &card)
+	}
+	response := fiber.Map{"card": card, "undo_token": undoToken}
+	if moved {
+		utils.EmitCardMovedWebhookEvent(&card, fromListID)
+		utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerCardMoved, CardID: card.ID, ListID: card.ListID, ActorID: userID})
+		blockedWarning := ""
+		if blockers := utils.CheckBlockedMove(&card, userID); len(blockers) > 0 {
+			blockedWarning = utils.BlockedMoveWarning(blockers)
+			response["blocked_by"] = blockers
+		}
+		if warning := utils.JoinWarnings(wipWarning, blockedWarning); warning != "" {
+			response["warning"] = warning
+		}
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	c.Set(fiber.HeaderETag, utils.ETag(card.Version))
+	return c.Status(fiber.StatusOK).JSON(response)
+}
 
+// DeleteCard deletes a card by its ID.
+func DeleteCard(c *fiber.Ctx) error {
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	card, ferr := loadEditableCard(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	before := captureUndo(utils.UndoEntityCard, utils.UndoKey{ID: card.ID})
+	if err := repositories.DeleteCard(card.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete card"})
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionDelete, utils.UndoKey{ID: card.ID}, before)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Card deleted successfully", "undo_token": undoToken})
 }
 
-// DeleteCard deletes a card by its ID.
-func DeleteCard(c *fiber.Ctx) error {
-	cardID, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+// CompleteCard marks a card as completed. Completing a recurring card creates its next
+// occurrence with the start date and deadline moved forward by the recurrence rule.
+func CompleteCard(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	if err := repositories.DeleteCard(uint(cardID)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete card"})
+	card, ferr := loadEditableCard(c, userID, "complete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if card.CompletedAt != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is already completed"})
+	}
+
+	before := captureUndo(utils.UndoEntityCard, utils.UndoKey{ID: card.ID})
+	now := time.Now()
+	next := utils.NewNextOccurrence(card, now)
+	warning := ""
+	if next != nil {
+		if warning, ferr = checkListWIPLimit(next.ListID, 1); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+	}
+	if err := repositories.CompleteCard(card, now, next); err != nil {
+		if errors.Is(err, repositories.ErrCardAlreadyCompleted) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is already completed"})
+		}
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Println("Error completing card:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to complete card"})
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionUpdate, utils.UndoKey{ID: card.ID}, before)
+	utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, card)
+	utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerCardCompleted, CardID: card.ID, ActorID: userID})
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	response := fiber.Map{"card": card, "undo_token": undoToken}
+	if next != nil {
+		var nextCard models.Card
+		if err := repositories.GetCardByID(next.ID, &nextCard); err == nil {
+			response["next_occurrence"] = nextCard
+		}
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// ReopenCard clears the completion of a card. A next occurrence already created for a
+// recurring card is kept.
+func ReopenCard(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadEditableCard(c, userID, "reopen")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if card.CompletedAt == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is not completed"})
+	}
+
+	before := captureUndo(utils.UndoEntityCard, utils.UndoKey{ID: card.ID})
+	if err := repositories.SetCardCompletedAt(card.ID, nil); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reopen card"})
+	}
+	card.CompletedAt = nil
+	card.Version++
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionUpdate, utils.UndoKey{ID: card.ID}, before)
+	utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, card)
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
 	}
 
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"card": card, "undo_token": undoToken})
+}
+
+// ArchiveCard archives a card, hiding it from the board without deleting it.
+func ArchiveCard(c *fiber.Ctx) error {
+	return setCardArchived(c, true)
+}
+
+// UnarchiveCard brings an archived card back to the board.
+func UnarchiveCard(c *fiber.Ctx) error {
+	return setCardArchived(c, false)
+}
+
+// setCardArchived archives or unarchives the card in the "id" route parameter.
+func setCardArchived(c *fiber.Ctx, archived bool) error {
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	action := "unarchive"
+	if archived {
+		action = "archive"
+	}
+	card, ferr := loadEditableCard(c, userID, action)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if archived == (card.ArchivedAt != nil) {
+		if archived {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is already archived"})
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Card is not archived"})
+	}
+
+	var archivedAt *time.Time
+	warning := ""
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	} else if warning, ferr = checkListWIPLimit(card.ListID, 1); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	before := captureUndo(utils.UndoEntityCard, utils.UndoKey{ID: card.ID})
+	if err := repositories.SetCardArchivedAt(card.ID, archivedAt); err != nil {
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to " + action + " card"})
+	}
+	card.ArchivedAt = archivedAt
+	card.Version++
+	undoToken := recordUndo(userID, utils.UndoEntityCard, utils.UndoActionUpdate, utils.UndoKey{ID: card.ID}, before)
+	utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, card)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Card deleted successfully"})
+	response := fiber.Map{"card": card, "undo_token": undoToken}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetOverdueCards returns the cards of a workspace whose deadline has passed.
+func GetOverdueCards(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var cards []models.Card
+	if err := repositories.GetOverdueCardsByWorkspace(uint(workspaceID), time.Now(), &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch overdue cards"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": cards})
+}
+
+// validateCardText checks the card title and Markdown description against the length limits.
+func validateCardText(title, description string) error {
+	if err := utils.ValidateLength("Title", title, utils.MaxCardTitleLength); err != nil {
+		return err
+	}
+	return utils.ValidateLength("Description", description, utils.MaxCardDescriptionLength)
+}
+
+// applyCardSchedule applies the optional "start_date", "recurrence" and "recurrence_interval"
+// form values to a card, returning the columns it changed, and checks that the start date is not
+// after the deadline. An empty start_date clears it and a recurrence of "none" makes the card a
+// one-off again.
+func applyCardSchedule(c *fiber.Ctx, card *models.Card) ([]string, error) {
+	var columns []string
+	if raw, ok := lookupFormValue(c, "start_date"); ok {
+		card.StartDate = nil
+		if raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, errors.New("Invalid start_date format")
+			}
+			card.StartDate = &parsed
+		}
+		columns = append(columns, "start_date")
+	}
+
+	if raw := c.FormValue("recurrence"); raw != "" {
+		if raw == "none" {
+			raw = ""
+		}
+		if !utils.IsValidRecurrence(raw) {
+			return nil, errors.New("recurrence must be one of daily, weekly, monthly or none")
+		}
+		card.Recurrence = raw
+		columns = append(columns, "recurrence")
+	}
+
+	if raw := c.FormValue("recurrence_interval"); raw != "" {
+		interval, err := strconv.Atoi(raw)
+		if err != nil || interval < 1 {
+			return nil, errors.New("recurrence_interval must be a positive number")
+		}
+		card.RecurrenceInterval = interval
+		columns = append(columns, "recurrence_interval")
+	}
+	if card.RecurrenceInterval < 1 {
+		card.RecurrenceInterval = 1
+	}
+
+	if card.StartDate != nil && card.Deadline != nil && card.StartDate.After(*card.Deadline) {
+		return nil, errors.New("start_date must not be after the deadline")
+	}
+	return columns, nil
+}
+
+// applyCardPriority applies the optional "priority" form value to a card, returning the columns
+// it changed.
+func applyCardPriority(c *fiber.Ctx, card *models.Card) ([]string, error) {
+	raw := c.FormValue("priority")
+	if raw == "" {
+		return nil, nil
+	}
+	if !utils.IsValidPriority(raw) {
+		return nil, errors.New("priority must be one of none, low, medium, high or urgent")
+	}
+	card.Priority = raw
+	return []string{"priority"}, nil
+}
+
+// checkListWIPLimit checks that adding cards keeps a list within its hard WIP limit, returning
+// the warning for a list that goes over its soft limit.
+func checkListWIPLimit(listID uint, adding int) (string, *fiber.Error) {
+	warning, err := utils.CheckWIPLimit(listID, adding)
+	if errors.Is(err, utils.ErrWIPLimitReached) {
+		return "", fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to check the list's WIP limit")
+	}
+	return warning, nil
+}
+
+// loadEditableCard loads the card in the "id" route parameter and checks that the user may
+// edit cards in its workspace. action completes the "Insufficient permission to ... card" error.
+func loadEditableCard(c *fiber.Ctx, userID uint, action string) (*models.Card, *fiber.Error) {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid card ID")
+	}
+
+	var card models.Card
+	if err := repositories.GetCardByID(uint(cardID), &card); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Card not found")
+	}
+
+	workspaceID, err := repositories.GetWorkspaceIDByListID(card.ListID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve workspace from list")
+	}
+	role, err := utils.CheckRoleInWorkspace(userID, workspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" card")
+	}
+	return &card, nil
+}
+
+// lookupFormValue returns a form-data or URL-encoded form field and whether it was sent at all,
+// so that partial updates can tell an omitted field from an empty one.
+func lookupFormValue(c *fiber.Ctx, key string) (string, bool) {
+	if form, err := c.MultipartForm(); err == nil {
+		values, ok := form.Value[key]
+		if !ok || len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	}
+
+	args := c.Request().PostArgs()
+	if !args.Has(key) {
+		return "", false
+	}
+	return string(args.Peek(key)), true
+}
+
+// respondVersionConflict rejects an update made against an outdated version of an item with
+// 409 Conflict, returning the current item under key and its ETag.
+func respondVersionConflict(c *fiber.Ctx, key string, current interface{}, version uint) error {
+	c.Set(fiber.HeaderETag, utils.ETag(version))
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error": "This item has been changed by someone else since you loaded it",
+		key:     current,
+	})
 }
@@ -12,20 +12,45 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateCardLabel adds a label to a card.
+// CreateCardLabel adds a workspace catalog label to a card.
+// Expects form-data "label_id", or "name" (and optional "color") to use the catalog label
+// with that name, creating it if it does not exist yet.
 func CreateCardLabel(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("card_id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID in route"})
 	}
 
-	name := c.FormValue("name")
-	color := c.FormValue("color") // optional
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(uint(cardID), &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to label card"})
+	}
+
+	catalogLabel, ferr := resolveCatalogLabel(c, card.List.WorkspaceID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if exists, _ := repositories.IsLabelOnCard(card.ID, catalogLabel.ID); exists {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Label is already on this card"})
+	}
 
 	label := models.CardLabel{
-		CardID:    uint(cardID),
-		Name:      name,
-		Color:     color,
+		CardID:    card.ID,
+		LabelID:   catalogLabel.ID,
 		CreatedAt: time.Now(),
 	}
 
@@ -33,17 +58,15 @@ func CreateCardLabel(c *fiber.Ctx) error {
 		log.Println("Error creating card label:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card label"})
 	}
-
-	userID, ok := c.Locals("user_id").(uint)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
+	label.Label = *catalogLabel
+	undoToken := recordUndo(userID, utils.UndoEntityCardLabel, utils.UndoActionCreate, utils.UndoKey{ID: label.ID}, "")
+	utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerLabelAdded, CardID: label.CardID, LabelID: label.LabelID, ActorID: userID})
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label, "undo_token": undoToken})
 }
 
 // GetLabels retrieves all labels for a given card.
@@ -75,7 +98,9 @@ func GetCardLabel(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label})
 }
 
-// UpdateCardLabel updates a card label.
+// UpdateCardLabel replaces the catalog label of a card label.
+// Accepts the same form-data as CreateCardLabel. To rename or recolor a label
+// on every card, update the catalog label instead.
 func UpdateCardLabel(c *fiber.Ctx) error {
 	labelID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -87,50 +112,74 @@ func UpdateCardLabel(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
 	}
 
-	newName := c.FormValue("name")
-	newColor := c.FormValue("color")
-	if newName != "" {
-		label.Name = newName
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
-	if newColor != "" {
-		label.Color = newColor
+
+	card, ferr := loadEditableCardByID(label.CardID, userID, "label")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 	}
 
-	if err := repositories.UpdateCardLabel(&label); err != nil {
-		log.Println("Error updating card label:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update label"})
+	catalogLabel, ferr := resolveCatalogLabel(c, card.List.WorkspaceID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 	}
 
-	userID, ok := c.Locals("user_id").(uint)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	undoToken := ""
+	if catalogLabel.ID != label.LabelID {
+		if exists, _ := repositories.IsLabelOnCard(label.CardID, catalogLabel.ID); exists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Label is already on this card"})
+		}
+
+		before := captureUndo(utils.UndoEntityCardLabel, utils.UndoKey{ID: label.ID})
+		label.LabelID = catalogLabel.ID
+		if err := repositories.UpdateCardLabel(&label); err != nil {
+			log.Println("Error updating card label:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update label"})
+		}
+		label.Label = *catalogLabel
+		undoToken = recordUndo(userID, utils.UndoEntityCardLabel, utils.UndoActionUpdate, utils.UndoKey{ID: label.ID}, before)
 	}
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label, "undo_token": undoToken})
 }
 
-// DeleteCardLabel deletes a card label by its ID.
+// DeleteCardLabel removes a label from a card by the card label's ID.
+// The catalog label itself is kept.
 func DeleteCardLabel(c *fiber.Ctx) error {
 	labelID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
 	}
-	if err := repositories.DeleteCardLabel(uint(labelID)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete label"})
-	}
 
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	var label models.CardLabel
+	if err := repositories.GetCardLabelByID(uint(labelID), &label); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
+	}
+	if _, ferr := loadEditableCardByID(label.CardID, userID, "unlabel"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	before := captureUndo(utils.UndoEntityCardLabel, utils.UndoKey{ID: uint(labelID)})
+	if err := repositories.DeleteCardLabel(uint(labelID)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete label"})
+	}
+	undoToken := recordUndo(userID, utils.UndoEntityCardLabel, utils.UndoActionDelete, utils.UndoKey{ID: uint(labelID)}, before)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Label deleted successfully"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Label deleted successfully", "undo_token": undoToken})
 }
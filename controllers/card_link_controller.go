@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"kelarin-backend/dto"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateCardLink links a card to another card of the same workspace.
+// Expects form-data "linked_card_id" and "type": blocks, blocked_by, relates_to, duplicates
+// or duplicated_by, read as "this card <type> the linked card". Blocking links that would make
+// a card block itself, directly or through other cards, are rejected.
+func CreateCardLink(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID in route"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(uint(cardID), &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to link card"})
+	}
+
+	linkedCardID, err := strconv.Atoi(c.FormValue("linked_card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid linked_card_id"})
+	}
+	var linked models.Card
+	if err := repositories.GetCardWithListByID(uint(linkedCardID), &linked); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Linked card not found"})
+	}
+
+	link, err := utils.NewCardLink(&card, &linked, c.FormValue("type"), userID)
+	if errors.Is(err, utils.ErrCardLinkExists) || errors.Is(err, utils.ErrCardLinkCycle) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	link.CreatedAt = time.Now()
+	if err := repositories.CreateCardLink(link); err != nil {
+		log.Println("Error creating card link:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create card link"})
+	}
+	link.Card, link.LinkedCard = card, linked
+	if link.CardID != card.ID {
+		link.Card, link.LinkedCard = linked, card
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"link": dto.NewCardLinkResponses(card.ID, []models.CardLink{*link})[0]})
+}
+
+// GetCardLinks returns the links of a card, seen from that card, and whether it is blocked by
+// a card that is not completed yet.
+func GetCardLinks(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_id"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(uint(cardID), &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	if _, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	links, ferr := loadCardLinks(card.ID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"links": links, "blocked": dto.IsBlocked(links)})
+}
+
+// DeleteCardLink removes a link between two cards.
+func DeleteCardLink(c *fiber.Ctx) error {
+	linkID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid link ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var link models.CardLink
+	if err := repositories.GetCardLinkByID(uint(linkID), &link); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card link not found"})
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(link.CardID, &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found"})
+	}
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to unlink card"})
+	}
+
+	if err := repositories.DeleteCardLink(link.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete card link"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Card link deleted successfully"})
+}
+
+// loadCardLinks loads the links of a card, seen from that card.
+func loadCardLinks(cardID uint) ([]dto.CardLinkResponse, *fiber.Error) {
+	var links []models.CardLink
+	if err := repositories.GetCardLinksByCard(cardID, &links); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch card links")
+	}
+	return dto.NewCardLinkResponses(cardID, links), nil
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// searchCursor identifies the last card of a search results page.
+type searchCursor struct {
+	Key string `json:"k"`
+	ID  uint   `json:"id"`
+}
+
+// SearchCards searches the cards of a workspace.
+// Query parameters (all optional):
+//   - q: full-text query over title, description and comments (supports "quotes", OR and -exclusions)
+//   - list_id, label_id, assignee_id: comma-separated IDs; a card matches if it has any of them
+//   - priority: comma-separated priorities (none, low, medium, high or urgent)
+//   - deadline_from, deadline_to: RFC3339 deadline range
+//   - overdue: "true" for cards whose deadline has passed
+//   - subtasks: "complete", "incomplete" or "none"
+//   - completed: "true" for completed cards only, "false" for open cards only
+//   - archived: "true" to include archived cards and lists
+//   - cf.<field_id>: custom field value; cf.<field_id>.min and cf.<field_id>.max bound number and
+//     date fields, and cf.<field_id>.set is "true" or "false" for cards with or without a value
+//   - sort: "created_at" (default), "updated_at", "deadline", "title", "priority" or "field:<field_id>";
+//     order: "asc" or "desc" (default)
+//   - limit (default 20, max 100) and cursor (the next_cursor of the previous page)
+func SearchCards(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	filter := repositories.CardSearchFilter{
+		WorkspaceID: uint(workspaceID),
+		Text:        strings.TrimSpace(c.Query("q")),
+		Overdue:     c.QueryBool("overdue", false),
+		Subtasks:    c.Query("subtasks"),
+		Archived:    c.QueryBool("archived", false),
+		Now:         time.Now(),
+		Sort:        c.Query("sort", repositories.CardSortCreatedAt),
+		Descending:  c.Query("order", "desc") != "asc",
+		Limit:       c.QueryInt("limit", 20),
+	}
+
+	if filter.ListIDs, err = parseIDList(c.Query("list_id")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list_id"})
+	}
+	if filter.LabelIDs, err = parseIDList(c.Query("label_id")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label_id"})
+	}
+	if filter.AssigneeIDs, err = parseIDList(c.Query("assignee_id")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid assignee_id"})
+	}
+	if raw := c.Query("priority"); raw != "" {
+		for _, priority := range strings.Split(raw, ",") {
+			priority = strings.TrimSpace(priority)
+			if !utils.IsValidPriority(priority) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "priority must be none, low, medium, high or urgent"})
+			}
+			filter.Priorities = append(filter.Priorities, priority)
+		}
+	}
+	if filter.DeadlineFrom, err = parseOptionalTime(c.Query("deadline_from")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid deadline_from format"})
+	}
+	if filter.DeadlineTo, err = parseOptionalTime(c.Query("deadline_to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid deadline_to format"})
+	}
+
+	if raw := c.Query("completed"); raw != "" {
+		completed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid completed value"})
+		}
+		filter.Completed = &completed
+	}
+
+	switch filter.Subtasks {
+	case "", repositories.SubtasksComplete, repositories.SubtasksIncomplete, repositories.SubtasksNone:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "subtasks must be complete, incomplete or none"})
+	}
+	if ferr := applyCustomFieldQuery(c, filter.WorkspaceID, &filter); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+	if filter.Sort != repositories.CardSortCustomField && !repositories.IsValidCardSort(filter.Sort) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sort must be created_at, updated_at, deadline, title, priority or field:<id>"})
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeSearchCursor(raw)
+		if err != nil || !isValidCursorKey(repositories.CardSortKeyType(filter), cursor.Key) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid cursor"})
+		}
+		filter.AfterKey, filter.AfterID = cursor.Key, cursor.ID
+	}
+
+	// Fetch one extra hit to know whether there is a next page.
+	limit := filter.Limit
+	filter.Limit++
+	hits, err := repositories.SearchCards(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search cards"})
+	}
+
+	nextCursor := ""
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		nextCursor = encodeSearchCursor(searchCursor{Key: last.SortKey, ID: last.ID})
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var cards []models.Card
+	if err := repositories.GetCardsByIDs(ids, &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch cards"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"cards":       cards,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseIDList parses a comma-separated list of IDs. An empty string yields no IDs.
+func parseIDList(raw string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// parseOptionalTime parses an RFC3339 time. An empty string yields nil.
+func parseOptionalTime(raw string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// encodeSearchCursor encodes a cursor as an opaque URL-safe string.
+func encodeSearchCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSearchCursor decodes a cursor produced by encodeSearchCursor.
+func decodeSearchCursor(raw string) (searchCursor, error) {
+	var cursor searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// cursorTimeLayouts are the formats a timestamptz sort key is accepted in: Postgres' text
+// output, with an hour or hour and minute offset, and RFC3339.
+var cursorTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999-07:00",
+	time.RFC3339Nano,
+}
+
+// isValidCursorKey reports whether a cursor's sort key can be cast to the Postgres type the
+// search sorts by, so a tampered or stale cursor is rejected instead of failing the query.
+func isValidCursorKey(keyType, key string) bool {
+	switch keyType {
+	case "timestamptz":
+		if key == "infinity" || key == "-infinity" {
+			return true
+		}
+		for _, layout := range cursorTimeLayouts {
+			if _, err := time.Parse(layout, key); err == nil {
+				return true
+			}
+		}
+		return false
+	case "int":
+		_, err := strconv.ParseInt(key, 10, 32)
+		return err == nil
+	case "float8":
+		_, err := strconv.ParseFloat(key, 64)
+		return err == nil
+	case "boolean":
+		_, err := strconv.ParseBool(key)
+		return err == nil
+	}
+	return utf8.ValidString(key) && !strings.ContainsRune(key, 0)
+}
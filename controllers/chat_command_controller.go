@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Discord interaction and response types used by slash commands.
+const (
+	discordInteractionPing        = 1
+	discordInteractionCommand     = 2
+	discordResponsePong           = 1
+	discordResponseChannelMessage = 4
+	discordMessageFlagEphemeral   = 64
+)
+
+// chatCommandUsage is appended to slash command replies when no card could be created.
+const chatCommandUsage = "Usage: /kelarin <title> | <description>"
+
+// CreateChatCommand sets up a Slack or Discord slash command that creates cards in a list of
+// the workspace. Only admins and owners can manage chat commands. Expects form-data:
+//   - provider: "slack" or "discord"
+//   - list_id: the list new cards are added to
+//   - signing_key: the Slack app's signing secret, or the Discord application's public key
+//   - enabled (optional, default true)
+//
+// The response includes the URL to configure as the slash command's request URL.
+func CreateChatCommand(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to create chat command"})
+	}
+
+	command := models.ChatCommand{
+		WorkspaceID: uint(workspaceID),
+		Enabled:     true,
+		CreatedByID: userID,
+	}
+	if ferr := applyChatCommandForm(c, &command); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.CreateChatCommand(&command); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create chat command"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"command": command, "url": chatCommandURL(c, command.ID)})
+}
+
+// GetChatCommands returns the chat slash commands of a workspace.
+func GetChatCommands(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+	if !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to view chat commands"})
+	}
+
+	var commands []models.ChatCommand
+	if err := repositories.GetChatCommandsByWorkspace(uint(workspaceID), &commands); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch chat commands"})
+	}
+
+	response := make([]fiber.Map, len(commands))
+	for i, command := range commands {
+		response[i] = fiber.Map{"command": command, "url": chatCommandURL(c, command.ID)}
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"commands": response})
+}
+
+// UpdateChatCommand updates a chat slash command. It accepts the same form-data as
+// CreateChatCommand; only the fields that are sent are changed.
+func UpdateChatCommand(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	command, ferr := loadManagedChatCommand(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if ferr := applyChatCommandForm(c, command); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.UpdateChatCommand(command); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update chat command"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"command": command, "url": chatCommandURL(c, command.ID)})
+}
+
+// DeleteChatCommand deletes a chat slash command.
+func DeleteChatCommand(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	command, ferr := loadManagedChatCommand(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.DeleteChatCommand(command.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete chat command"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Chat command deleted successfully"})
+}
+
+// HandleChatCommand receives a slash command from Slack or Discord and creates a card from its
+// text, "title | description", in the command's list. The request is authenticated by the chat
+// provider's signature rather than a user token, and the reply is shown in the chat.
+func HandleChatCommand(c *fiber.Ctx) error {
+	commandID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid chat command ID"})
+	}
+
+	var command models.ChatCommand
+	if err := repositories.GetChatCommandByID(uint(commandID), &command); err != nil || !command.Enabled {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chat command not found"})
+	}
+
+	switch command.Provider {
+	case models.ChatProviderSlack:
+		return handleSlackCommand(c, &command)
+	case models.ChatProviderDiscord:
+		return handleDiscordCommand(c, &command)
+	}
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Chat command not found"})
+}
+
+// handleSlackCommand verifies and answers a Slack slash command, sent as URL-encoded form data.
+// Slack only shows replies to successful requests, so card errors are answered with 200 and an
+// ephemeral message.
+func handleSlackCommand(c *fiber.Ctx, command *models.ChatCommand) error {
+	timestamp := c.Get("X-Slack-Request-Timestamp")
+	signature := c.Get("X-Slack-Signature")
+	if !utils.VerifySlackRequest(command.SigningKey, timestamp, signature, c.Body(), time.Now()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid request signature"})
+	}
+
+	title, description := utils.ParseChatCardText(c.FormValue("text"))
+	card, err := utils.CreateCardFromChat(command, title, description)
+	if err != nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"response_type": "ephemeral",
+			"text":          "Could not create the card: " + err.Error() + ". " + chatCommandUsage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"response_type": "in_channel",
+		"text":          chatCardCreatedMessage(card),
+	})
+}
+
+// handleDiscordCommand verifies and answers a Discord interaction, sent as JSON. The card text
+// is read from a "title" option with an optional "description" option, or from a single "text"
+// option in the "title | description" form.
+func handleDiscordCommand(c *fiber.Ctx, command *models.ChatCommand) error {
+	timestamp := c.Get("X-Signature-Timestamp")
+	signature := c.Get("X-Signature-Ed25519")
+	if !utils.VerifyDiscordRequest(command.SigningKey, timestamp, signature, c.Body(), time.Now()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid request signature"})
+	}
+
+	var interaction struct {
+		Type int `json:"type"`
+		Data struct {
+			Options []struct {
+				Name  string      `json:"name"`
+				Value interface{} `json:"value"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(c.Body(), &interaction); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid interaction payload"})
+	}
+
+	switch interaction.Type {
+	case discordInteractionPing:
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"type": discordResponsePong})
+	case discordInteractionCommand:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Unsupported interaction type"})
+	}
+
+	var title, description string
+	for _, option := range interaction.Data.Options {
+		value, _ := option.Value.(string)
+		switch option.Name {
+		case "title":
+			title = strings.TrimSpace(value)
+		case "description":
+			description = strings.TrimSpace(value)
+		case "text":
+			title, description = utils.ParseChatCardText(value)
+		}
+	}
+
+	card, err := utils.CreateCardFromChat(command, title, description)
+	if err != nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"type": discordResponseChannelMessage,
+			"data": fiber.Map{
+				"content": "Could not create the card: " + err.Error() + ". " + chatCommandUsage,
+				"flags":   discordMessageFlagEphemeral,
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"type": discordResponseChannelMessage,
+		"data": fiber.Map{"content": chatCardCreatedMessage(card)},
+	})
+}
+
+// chatCardCreatedMessage is the chat reply to a card created by a slash command.
+func chatCardCreatedMessage(card *models.Card) string {
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(card.ListID, &list); err != nil {
+		return fmt.Sprintf("Created card %q", card.Title)
+	}
+	return fmt.Sprintf("Created card %q in %q", card.Title, list.Title)
+}
+
+// chatCommandURL returns the request URL to configure for a chat slash command.
+func chatCommandURL(c *fiber.Ctx, id uint) string {
+	return c.BaseURL() + "/api/integrations/chat/commands/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// loadManagedChatCommand loads the chat command in the "id" route parameter and checks that the
+// user is an admin or owner of its workspace.
+func loadManagedChatCommand(c *fiber.Ctx, userID uint, action string) (*models.ChatCommand, *fiber.Error) {
+	commandID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid chat command ID")
+	}
+
+	var command models.ChatCommand
+	if err := repositories.GetChatCommandByID(uint(commandID), &command); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Chat command not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, command.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" chat command")
+	}
+	return &command, nil
+}
+
+// applyChatCommandForm applies the chat command fields present in the form to a command and
+// validates the result.
+func applyChatCommandForm(c *fiber.Ctx, command *models.ChatCommand) *fiber.Error {
+	if provider, ok := lookupFormValue(c, "provider"); ok {
+		command.Provider = provider
+	}
+	if key, ok := lookupFormValue(c, "signing_key"); ok {
+		command.SigningKey = strings.TrimSpace(key)
+	}
+	if err := utils.ValidateChatSigningKey(command.Provider, command.SigningKey); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if raw, ok := lookupFormValue(c, "list_id"); ok {
+		listID, err := strconv.Atoi(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid list_id")
+		}
+		command.ListID = uint(listID)
+	}
+	workspaceID, err := repositories.GetWorkspaceIDByListID(command.ListID)
+	if err != nil || workspaceID != command.WorkspaceID {
+		return fiber.NewError(fiber.StatusBadRequest, "list_id must be a list in this workspace")
+	}
+
+	if raw, ok := lookupFormValue(c, "enabled"); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid enabled value")
+		}
+		command.Enabled = enabled
+	}
+	return nil
+}
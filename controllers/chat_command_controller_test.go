@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kelarin-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendChatCommand sends a slash command request to the handler of command's provider and
+// decodes the JSON reply. Only requests rejected before a card is created are sent, so no
+// database is needed.
+func sendChatCommand(t *testing.T, command *models.ChatCommand, req *http.Request) (int, map[string]interface{}) {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		if command.Provider == models.ChatProviderDiscord {
+			return handleDiscordCommand(c, command)
+		}
+		return handleSlackCommand(c, command)
+	})
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var reply map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, reply
+}
+
+// slackRequest returns a Slack slash command request signed with secret.
+func slackRequest(secret, text string, signedAt time.Time) *http.Request {
+	body := url.Values{"command": {"/kelarin"}, "text": {text}}.Encode()
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+// discordRequest returns a Discord interaction request signed with key.
+func discordRequest(key ed25519.PrivateKey, interaction string) *http.Request {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := ed25519.Sign(key, []byte(timestamp+interaction))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(interaction))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+	return req
+}
+
+func TestHandleSlackCommandRejectsUnsignedRequests(t *testing.T) {
+	command := &models.ChatCommand{Provider: models.ChatProviderSlack, SigningKey: "secret", Enabled: true}
+
+	status, _ := sendChatCommand(t, command, slackRequest("wrong", "Fix login", time.Now()))
+	if status != fiber.StatusUnauthorized {
+		t.Errorf("status with a bad signature = %d, want 401", status)
+	}
+	status, _ = sendChatCommand(t, command, slackRequest("secret", "Fix login", time.Now().Add(-10*time.Minute)))
+	if status != fiber.StatusUnauthorized {
+		t.Errorf("status with a stale timestamp = %d, want 401", status)
+	}
+}
+
+func TestHandleDiscordCommand(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := &models.ChatCommand{Provider: models.ChatProviderDiscord, SigningKey: hex.EncodeToString(publicKey), Enabled: true}
+
+	status, reply := sendChatCommand(t, command, discordRequest(privateKey, `{"type":1}`))
+	if status != fiber.StatusOK || reply["type"] != float64(discordResponsePong) {
+		t.Errorf("ping reply = %d %v, want a pong", status, reply)
+	}
+
+	status, _ = sendChatCommand(t, command, discordRequest(privateKey, `{"type":3}`))
+	if status != fiber.StatusBadRequest {
+		t.Errorf("status for an unsupported interaction = %d, want 400", status)
+	}
+	status, _ = sendChatCommand(t, command, discordRequest(privateKey, `{"type":`))
+	if status != fiber.StatusBadRequest {
+		t.Errorf("status for a malformed interaction = %d, want 400", status)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	status, _ = sendChatCommand(t, command, discordRequest(otherKey, `{"type":1}`))
+	if status != fiber.StatusUnauthorized {
+		t.Errorf("status with a bad signature = %d, want 401", status)
+	}
+}
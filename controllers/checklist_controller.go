@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateChecklist adds a named checklist to the end of a card's checklists.
+// Expects form-data "title".
+func CreateChecklist(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID in route"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, ferr := loadEditableCardByID(uint(cardID), userID, "add checklist to"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	title := c.FormValue("title")
+	if title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+	}
+	if err := utils.ValidateLength("Title", title, utils.MaxCardTitleLength); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	position, err := repositories.NextChecklistPosition(uint(cardID))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create checklist"})
+	}
+
+	now := time.Now()
+	checklist := models.Checklist{
+		CardID:    uint(cardID),
+		Title:     title,
+		Position:  position,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Items:     []models.Subtask{},
+	}
+	if err := repositories.CreateChecklist(&checklist); err != nil {
+		log.Println("Error creating checklist:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create checklist"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"checklist": checklist})
+}
+
+// GetChecklists returns the checklists of a card in order with their items, the card's
+// subtasks that are not in a checklist, and the completion percentage of the card.
+func GetChecklists(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card_id"})
+	}
+
+	var checklists []models.Checklist
+	if err := repositories.GetChecklistsByCard(uint(cardID), &checklists); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checklists"})
+	}
+
+	var subtasks []models.Subtask
+	if err := repositories.GetSubtasksByCard(uint(cardID), &subtasks); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch subtasks"})
+	}
+	ungrouped := make([]models.Subtask, 0)
+	for _, subtask := range subtasks {
+		if subtask.ChecklistID == nil {
+			ungrouped = append(ungrouped, subtask)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"checklists":         checklists,
+		"subtasks":           ungrouped,
+		"completion_percent": utils.CompletionPercent(subtasks),
+	})
+}
+
+// UpdateChecklist renames a checklist. Expects form-data "title".
+func UpdateChecklist(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	checklist, ferr := loadEditableChecklist(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	title := c.FormValue("title")
+	if title == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+	}
+	if err := utils.ValidateLength("Title", title, utils.MaxCardTitleLength); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := repositories.UpdateChecklistTitle(checklist.ID, title); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update checklist"})
+	}
+	checklist.Title = title
+	checklist.UpdatedAt = time.Now()
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"checklist": checklist})
+}
+
+// DeleteChecklist deletes a checklist together with its items.
+func DeleteChecklist(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	checklist, ferr := loadEditableChecklist(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.DeleteChecklist(checklist.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete checklist"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Checklist deleted successfully"})
+}
+
+// ReorderChecklists sets the order of a card's checklists.
+// Expects form-data "checklist_ids": every checklist ID of the card, comma-separated, in the new order.
+func ReorderChecklists(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID in route"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, ferr := loadEditableCardByID(uint(cardID), userID, "reorder checklists of"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	ids, err := parseIDList(c.FormValue("checklist_ids"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid checklist_ids"})
+	}
+
+	var checklists []models.Checklist
+	if err := repositories.GetChecklistsByCard(uint(cardID), &checklists); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checklists"})
+	}
+	current := make([]uint, len(checklists))
+	for i, checklist := range checklists {
+		current[i] = checklist.ID
+	}
+	if !isPermutation(ids, current) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "checklist_ids must list every checklist of the card exactly once"})
+	}
+
+	if err := repositories.ReorderChecklists(uint(cardID), ids); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reorder checklists"})
+	}
+	if err := repositories.GetChecklistsByCard(uint(cardID), &checklists); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checklists"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"checklists": checklists})
+}
+
+// ReorderChecklistItems sets the order of the items of a checklist.
+// Expects form-data "item_ids": every item ID of the checklist, comma-separated, in the new order.
+func ReorderChecklistItems(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	checklist, ferr := loadEditableChecklist(c, userID, "reorder")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	ids, err := parseIDList(c.FormValue("item_ids"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid item_ids"})
+	}
+
+	current := make([]uint, len(checklist.Items))
+	for i, item := range checklist.Items {
+		current[i] = item.ID
+	}
+	if !isPermutation(ids, current) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "item_ids must list every item of the checklist exactly once"})
+	}
+
+	if err := repositories.ReorderSubtasks(checklist.ID, ids); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reorder checklist items"})
+	}
+	if err := repositories.GetChecklistByID(checklist.ID, checklist); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch checklist"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"checklist": checklist})
+}
+
+// ConvertSubtaskToCard turns a checklist item into a card of its own in the same list, with
+// the item's title, due date as deadline and assignee. The item is removed and the new card
+// is linked to the original card with a relates_to link. The list's WIP limit applies as when
+// creating a card.
+func ConvertSubtaskToCard(c *fiber.Ctx) error {
+	subtaskID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var subtask models.Subtask
+	if err := repositories.GetSubtaskByID(uint(subtaskID), &subtask); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+	}
+
+	parent, ferr := loadEditableCardByID(subtask.CardID, userID, "convert subtask of")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	warning, ferr := checkListWIPLimit(parent.ListID, 1)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	card, link := utils.NewCardFromSubtask(&subtask, parent, userID, time.Now())
+	if err := repositories.ConvertSubtaskToCard(&subtask, card, link); err != nil {
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Println("Error converting subtask to card:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to convert subtask to card"})
+	}
+	if err := repositories.GetCardByID(card.ID, card); err != nil {
+		log.Println("Error reloading converted card:", err)
+	}
+	utils.EmitCardWebhookEvent(models.WebhookEventCardCreated, card)
+	utils.DispatchAutomationEvent(utils.AutomationEvent{Trigger: models.AutomationTriggerCardCreated, CardID: card.ID, ListID: card.ListID, ActorID: userID})
+	if card.CompletedAt == nil {
+		// Converting an open item may leave the parent with only done subtasks.
+		utils.DispatchSubtaskDone(parent.ID, userID)
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	response := fiber.Map{"card": card}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// loadEditableChecklist loads the checklist in the "id" route parameter with its items and
+// checks that the user may edit cards in its workspace. action completes the
+// "Insufficient permission to ... checklist" error.
+func loadEditableChecklist(c *fiber.Ctx, userID uint, action string) (*models.Checklist, *fiber.Error) {
+	checklistID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid checklist ID")
+	}
+
+	var checklist models.Checklist
+	if err := repositories.GetChecklistByID(uint(checklistID), &checklist); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Checklist not found")
+	}
+
+	if _, ferr := loadEditableCardByID(checklist.CardID, userID, action+" checklist of"); ferr != nil {
+		return nil, ferr
+	}
+	return &checklist, nil
+}
+
+// loadEditableCardByID loads a card with its list and checks that the user may edit cards in
+// its workspace. action completes the "Insufficient permission to ... card" error.
+func loadEditableCardByID(cardID, userID uint, action string) (*models.Card, *fiber.Error) {
+	var card models.Card
+	if err := repositories.GetCardWithListByID(cardID, &card); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Card not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" card")
+	}
+	return &card, nil
+}
+
+// isPermutation reports whether ids holds exactly the IDs of current, each once, in any order.
+func isPermutation(ids, current []uint) bool {
+	if len(ids) != len(current) {
+		return false
+	}
+	remaining := make(map[uint]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range ids {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
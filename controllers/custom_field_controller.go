@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// customFieldSortPrefix marks a sort on a custom field, as in "field:12".
+const customFieldSortPrefix = "field:"
+
+// customFieldQueryPrefix starts the query parameters that filter on a custom field:
+// "cf.<id>=<value>", "cf.<id>.min=<value>", "cf.<id>.max=<value>" and "cf.<id>.set=true|false".
+const customFieldQueryPrefix = "cf."
+
+// CreateCustomField adds a custom field to the cards of a workspace.
+// Expects form-data "name", "type" (text, number, date, select, multi_select, checkbox or user)
+// and, for select and multi_select fields, "options" as a JSON array or comma-separated list.
+func CreateCustomField(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to create custom field"})
+	}
+
+	options, err := utils.ParseCustomFieldOptions(c.FormValue("options"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	field := models.CustomField{
+		WorkspaceID: uint(workspaceID),
+		Name:        c.FormValue("name"),
+		Type:        c.FormValue("type"),
+		Options:     options,
+	}
+	if err := utils.ValidateCustomField(&field); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if field.Position, err = repositories.NextCustomFieldPosition(field.WorkspaceID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create custom field"})
+	}
+	if err := repositories.CreateCustomField(&field); err != nil {
+		log.Println("Error creating custom field:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create custom field"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"field": field})
+}
+
+// GetCustomFields returns the custom fields of a workspace in order.
+func GetCustomFields(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var fields []models.CustomField
+	if err := repositories.GetCustomFieldsByWorkspace(uint(workspaceID), &fields); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch custom fields"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"fields": fields})
+}
+
+// UpdateCustomField renames a custom field or changes its options. The type of a field cannot
+// change. Card values that are no longer one of the options are removed.
+func UpdateCustomField(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	field, ferr := loadManagedCustomField(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if fieldType, ok := lookupFormValue(c, "type"); ok && fieldType != field.Type {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "The type of a custom field cannot be changed"})
+	}
+
+	var columns []string
+	if name, ok := lookupFormValue(c, "name"); ok {
+		field.Name = name
+		columns = append(columns, "name")
+	}
+	optionsChanged := false
+	if raw, ok := lookupFormValue(c, "options"); ok {
+		options, err := utils.ParseCustomFieldOptions(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		field.Options = options
+		columns = append(columns, "options")
+		optionsChanged = true
+	}
+	if err := utils.ValidateCustomField(field); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(columns) > 0 {
+		field.UpdatedAt = time.Now()
+		if err := repositories.UpdateCustomField(field, columns); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update custom field"})
+		}
+	}
+	if optionsChanged {
+		if err := utils.PruneCustomFieldValues(field); err != nil {
+			log.Println("Error pruning custom field values:", err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"field": field})
+}
+
+// DeleteCustomField deletes a custom field and its values on every card.
+func DeleteCustomField(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	field, ferr := loadManagedCustomField(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.DeleteCustomField(field.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete custom field"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Custom field deleted successfully"})
+}
+
+// SetCardCustomFieldValue sets the value of a custom field on a card.
+// Expects form-data "value", in the form its field type takes; an empty value clears it.
+func SetCardCustomFieldValue(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, field, ferr := loadCardCustomField(c, userID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	raw := c.FormValue("value")
+	if raw == "" {
+		return clearCardCustomFieldValue(c, userID, card, field)
+	}
+
+	value, err := utils.NormalizeCustomFieldValue(field, raw)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	fieldValue := models.CardCustomFieldValue{CardID: card.ID, FieldID: field.ID, Value: value}
+	if err := repositories.SetCardCustomFieldValue(&fieldValue); err != nil {
+		log.Println("Error setting custom field value:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to set custom field value"})
+	}
+	fieldValue.Field = field
+	utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, card)
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"custom_field": fieldValue})
+}
+
+// DeleteCardCustomFieldValue clears the value of a custom field on a card.
+func DeleteCardCustomFieldValue(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, field, ferr := loadCardCustomField(c, userID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	return clearCardCustomFieldValue(c, userID, card, field)
+}
+
+// clearCardCustomFieldValue removes the value of a custom field from a card and writes the response.
+func clearCardCustomFieldValue(c *fiber.Ctx, userID uint, card *models.Card, field *models.CustomField) error {
+	if err := repositories.DeleteCardCustomFieldValue(card.ID, field.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear custom field value"})
+	}
+	utils.EmitCardWebhookEvent(models.WebhookEventCardUpdated, card)
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Custom field value cleared successfully"})
+}
+
+// loadManagedCustomField loads the custom field in the "id" route parameter and checks that the
+// user is an admin or owner of its workspace. action completes the
+// "Insufficient permission to ... custom field" error.
+func loadManagedCustomField(c *fiber.Ctx, userID uint, action string) (*models.CustomField, *fiber.Error) {
+	fieldID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid custom field ID")
+	}
+
+	var field models.CustomField
+	if err := repositories.GetCustomFieldByID(uint(fieldID), &field); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Custom field not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, field.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" custom field")
+	}
+	return &field, nil
+}
+
+// loadCardCustomField loads the card and custom field in the "card_id" and "field_id" route
+// parameters, checking that the field belongs to the card's workspace and that the user may
+// edit its cards.
+func loadCardCustomField(c *fiber.Ctx, userID uint) (*models.Card, *models.CustomField, *fiber.Error) {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid card ID in route")
+	}
+	fieldID, err := strconv.Atoi(c.Params("field_id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid custom field ID")
+	}
+
+	card, ferr := loadEditableCardByID(uint(cardID), userID, "update")
+	if ferr != nil {
+		return nil, nil, ferr
+	}
+
+	var field models.CustomField
+	if err := repositories.GetCustomFieldByID(uint(fieldID), &field); err != nil || field.WorkspaceID != card.List.WorkspaceID {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "Custom field not found")
+	}
+	return card, &field, nil
+}
+
+// applyCustomFieldQuery adds the custom field filters in the "cf.*" query parameters, and a
+// "field:<id>" sort, to a card search of a workspace.
+func applyCustomFieldQuery(c *fiber.Ctx, workspaceID uint, filter *repositories.CardSearchFilter) *fiber.Error {
+	sortField := strings.HasPrefix(filter.Sort, customFieldSortPrefix)
+	if filter.Sort == repositories.CardSortCustomField {
+		return fiber.NewError(fiber.StatusBadRequest, "Sort by a custom field with field:<id>")
+	}
+
+	type rawFilter struct{ key, value string }
+	var raws []rawFilter
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if strings.HasPrefix(string(key), customFieldQueryPrefix) {
+			raws = append(raws, rawFilter{string(key), string(value)})
+		}
+	})
+	if !sortField && len(raws) == 0 {
+		return nil
+	}
+
+	var fields []models.CustomField
+	if err := repositories.GetCustomFieldsByWorkspace(workspaceID, &fields); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch custom fields")
+	}
+	byID := make(map[uint]*models.CustomField, len(fields))
+	for i := range fields {
+		byID[fields[i].ID] = &fields[i]
+	}
+
+	if sortField {
+		id, err := strconv.Atoi(strings.TrimPrefix(filter.Sort, customFieldSortPrefix))
+		field, ok := byID[uint(id)]
+		if err != nil || !ok {
+			return fiber.NewError(fiber.StatusBadRequest, "sort must name a custom field of this workspace")
+		}
+		filter.Sort, filter.SortField, filter.SortFieldType = repositories.CardSortCustomField, field.ID, field.Type
+	}
+
+	for _, raw := range raws {
+		parts := strings.SplitN(strings.TrimPrefix(raw.key, customFieldQueryPrefix), ".", 2)
+		id, err := strconv.Atoi(parts[0])
+		field, ok := byID[uint(id)]
+		if err != nil || !ok {
+			return fiber.NewError(fiber.StatusBadRequest, raw.key+" must name a custom field of this workspace")
+		}
+		op := repositories.CustomFieldOpEquals
+		if len(parts) == 2 {
+			op = parts[1]
+		}
+
+		cf, err := utils.NewCustomFieldFilter(field, op, raw.value)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid "+raw.key+": "+err.Error())
+		}
+		filter.CustomFields = append(filter.CustomFields, cf)
+	}
+	return nil
+}
+
+// hasCardQuery reports whether a list's cards are requested with a sort or custom field filters.
+func hasCardQuery(c *fiber.Ctx) bool {
+	if c.Query("sort") != "" {
+		return true
+	}
+	found := false
+	c.Context().QueryArgs().VisitAll(func(key, _ []byte) {
+		found = found || strings.HasPrefix(string(key), customFieldQueryPrefix)
+	})
+	return found
+}
+
+// queryListCards loads the cards of a list matching the custom field filters and sort of the request.
+func queryListCards(c *fiber.Ctx, listID uint, cards *[]models.Card) *fiber.Error {
+	workspaceID, err := repositories.GetWorkspaceIDByListID(listID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "List not found")
+	}
+
+	filter := repositories.CardSearchFilter{
+		WorkspaceID: workspaceID,
+		ListIDs:     []uint{listID},
+		Archived:    c.QueryBool("include_archived", false),
+		Now:         time.Now(),
+		Sort:        c.Query("sort", repositories.CardSortCreatedAt),
+		Descending:  c.Query("order", "asc") == "desc",
+	}
+	if ferr := applyCustomFieldQuery(c, workspaceID, &filter); ferr != nil {
+		return ferr
+	}
+	if filter.Sort != repositories.CardSortCustomField && !repositories.IsValidCardSort(filter.Sort) {
+		return fiber.NewError(fiber.StatusBadRequest, "sort must be created_at, updated_at, deadline, title, priority or field:<id>")
+	}
+
+	hits, err := repositories.SearchCards(filter)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch cards")
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	if err := repositories.GetCardsByIDs(ids, cards); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch cards")
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/inbound"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// postmarkInboundEmail is the JSON payload of Postmark-style inbound webhooks.
+type postmarkInboundEmail struct {
+	From              string `json:"From"`
+	To                string `json:"To"`
+	Cc                string `json:"Cc"`
+	OriginalRecipient string `json:"OriginalRecipient"`
+	Subject           string `json:"Subject"`
+	TextBody          string `json:"TextBody"`
+	HtmlBody          string `json:"HtmlBody"`
+	Attachments       []struct {
+		Name        string `json:"Name"`
+		Content     string `json:"Content"` // base64
+		ContentType string `json:"ContentType"`
+	} `json:"Attachments"`
+}
+
+// GetListInboundAddress returns the inbound email address of a board list, creating it on first use.
+func GetListInboundAddress(c *fiber.Ctx) error {
+	return listInboundAddress(c, false)
+}
+
+// RegenerateListInboundAddress replaces the inbound email address of a board list, so emails to
+// the previous address no longer create cards.
+func RegenerateListInboundAddress(c *fiber.Ctx) error {
+	return listInboundAddress(c, true)
+}
+
+// listInboundAddress returns the inbound address of the list in the "id" route parameter,
+// creating its token if it does not exist yet or if regenerate is set. Any workspace member may
+// read the address; creating or regenerating it requires editor access.
+func listInboundAddress(c *fiber.Ctx, regenerate bool) error {
+	listID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(uint(listID), &list); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "List not found"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, list.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var address models.ListInboundAddress
+	err = repositories.GetInboundAddressByListID(list.ID, &address)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch inbound address"})
+	}
+
+	if err != nil || regenerate {
+		if !utils.IsEditorAdminOwner(role) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to generate an inbound address"})
+		}
+
+		value, err := utils.GenerateInboundToken()
+		if err != nil {
+			log.Println("Error generating inbound token:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate inbound address"})
+		}
+
+		address = models.ListInboundAddress{
+			ListID:    list.ID,
+			Token:     value,
+			CreatedAt: time.Now(),
+		}
+		if err := repositories.ReplaceInboundAddress(&address); err != nil {
+			log.Println("Error saving inbound address:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate inbound address"})
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"list_id":    list.ID,
+		"address":    utils.InboundAddress(address.Token),
+		"created_at": address.CreatedAt,
+	})
+}
+
+// ReceiveInboundEmail creates cards from an email already parsed by an inbound-mail provider.
+// It accepts Postmark-style JSON, and SendGrid- or Mailgun-style multipart forms (a SendGrid
+// "email" field with the raw message is parsed as MIME). The request must carry the
+// INBOUND_EMAIL_SECRET in the "key" query parameter or the X-Inbound-Secret header.
+func ReceiveInboundEmail(c *fiber.Ctx) error {
+	if ferr := checkInboundSecret(c); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var email *inbound.Email
+	var err error
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		email, err = parsePostmarkEmail(c.Body())
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm), strings.HasPrefix(contentType, fiber.MIMEApplicationForm):
+		email, err = parseFormEmail(c)
+	default:
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": "Expected a JSON or form payload"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid inbound email: " + err.Error()})
+	}
+
+	return createInboundCards(c, email)
+}
+
+// ReceiveRawInboundEmail creates cards from a raw MIME message sent as the request body, for
+// providers that forward the original message and for local testing. It requires the same
+// secret as ReceiveInboundEmail.
+func ReceiveRawInboundEmail(c *fiber.Ctx) error {
+	if ferr := checkInboundSecret(c); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	email, err := inbound.ParseMIME(bytes.NewReader(c.Body()))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid MIME message: " + err.Error()})
+	}
+
+	return createInboundCards(c, email)
+}
+
+// createInboundCards creates the cards of an inbound email and writes the response.
+// Unknown recipients are answered with 406 Not Acceptable, which providers treat as a
+// permanent rejection instead of retrying.
+func createInboundCards(c *fiber.Ctx, email *inbound.Email) error {
+	cards, err := utils.ProcessInboundEmail(email)
+	if errors.Is(err, utils.ErrNoInboundList) {
+		return c.Status(fiber.StatusNotAcceptable).JSON(fiber.Map{"error": "No list matches the recipients"})
+	}
+	if err != nil {
+		log.Println("Error processing inbound email:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create cards from email"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": cards})
+}
+
+// checkInboundSecret verifies the shared inbound-mail secret of a request.
+func checkInboundSecret(c *fiber.Ctx) *fiber.Error {
+	secret := env.Get("INBOUND_EMAIL_SECRET", "")
+	if secret == "" {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Inbound email is not configured")
+	}
+
+	given := c.Get("X-Inbound-Secret")
+	if given == "" {
+		given = c.Query("key")
+	}
+	if subtle.ConstantTimeCompare([]byte(given), []byte(secret)) != 1 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid inbound secret")
+	}
+	return nil
+}
+
+// parsePostmarkEmail parses a Postmark-style JSON inbound payload.
+func parsePostmarkEmail(body []byte) (*inbound.Email, error) {
+	var payload postmarkInboundEmail
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	email := &inbound.Email{
+		From:    payload.From,
+		Subject: payload.Subject,
+		Text:    payload.TextBody,
+		HTML:    payload.HtmlBody,
+	}
+	for _, field := range []string{payload.To, payload.Cc, payload.OriginalRecipient} {
+		email.To = append(email.To, inbound.ParseAddresses(field)...)
+	}
+	for _, attachment := range payload.Attachments {
+		data, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			return nil, errors.New("attachment " + attachment.Name + " is not valid base64")
+		}
+		email.Attachments = append(email.Attachments, inbound.Attachment{
+			FileName:    attachment.Name,
+			ContentType: attachment.ContentType,
+			Data:        data,
+		})
+	}
+	return email, nil
+}
+
+// parseFormEmail parses a SendGrid- or Mailgun-style form inbound payload. Every uploaded file
+// is taken as an attachment, whatever its field name.
+func parseFormEmail(c *fiber.Ctx) (*inbound.Email, error) {
+	if raw := c.FormValue("email"); raw != "" {
+		return inbound.ParseMIME(strings.NewReader(raw))
+	}
+
+	email := &inbound.Email{
+		From:    inbound.DecodeHeader(firstFormValue(c, "from", "sender")),
+		Subject: inbound.DecodeHeader(c.FormValue("subject")),
+		Text:    firstFormValue(c, "text", "body-plain"),
+		HTML:    firstFormValue(c, "html", "body-html"),
+	}
+	for _, field := range []string{"to", "cc", "recipient", "To", "Cc"} {
+		email.To = append(email.To, inbound.ParseAddresses(c.FormValue(field))...)
+	}
+	if envelope := c.FormValue("envelope"); envelope != "" {
+		var parsed struct {
+			To []string `json:"to"`
+		}
+		if err := json.Unmarshal([]byte(envelope), &parsed); err == nil {
+			for _, to := range parsed.To {
+				email.To = append(email.To, strings.ToLower(to))
+			}
+		}
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		// URL-encoded forms carry no files.
+		return email, nil
+	}
+	for _, files := range form.File {
+		for _, header := range files {
+			file, err := header.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			email.Attachments = append(email.Attachments, inbound.Attachment{
+				FileName:    header.Filename,
+				ContentType: header.Header.Get(fiber.HeaderContentType),
+				Data:        data,
+			})
+		}
+	}
+	return email, nil
+}
+
+// firstFormValue returns the value of the first of the given form fields that is set.
+func firstFormValue(c *fiber.Ctx, keys ...string) string {
+	for _, key := range keys {
+		if value := c.FormValue(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
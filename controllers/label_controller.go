@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateLabel adds a label to a workspace's label catalog.
+// Expects form-data "name" and optional "color" ("#RGB" or "#RRGGBB").
+func CreateLabel(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to create label"})
+	}
+
+	label, ferr := createCatalogLabel(uint(workspaceID), c.FormValue("name"), c.FormValue("color"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label})
+}
+
+// GetWorkspaceLabels returns the label catalog of a workspace.
+func GetWorkspaceLabels(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var labels []models.Label
+	if err := repositories.GetLabelsByWorkspace(uint(workspaceID), &labels); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch labels"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"labels": labels})
+}
+
+// UpdateLabel renames or recolors a catalog label. The change applies to every card using it.
+func UpdateLabel(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+	labelID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to update label"})
+	}
+
+	var label models.Label
+	if err := repositories.GetLabelByID(uint(labelID), &label); err != nil || label.WorkspaceID != uint(workspaceID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
+	}
+
+	if name := strings.TrimSpace(c.FormValue("name")); name != "" {
+		if err := utils.ValidateLength("Name", name, utils.MaxLabelNameLength); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		taken, err := repositories.IsLabelNameTaken(label.WorkspaceID, name, label.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check label name"})
+		}
+		if taken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A label with this name already exists"})
+		}
+		label.Name = name
+	}
+	if color := strings.TrimSpace(c.FormValue("color")); color != "" {
+		if !utils.IsValidHexColor(color) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Color must be a hex code like #FF0000"})
+		}
+		label.Color = strings.ToUpper(color)
+	}
+	label.UpdatedAt = time.Now()
+
+	if err := repositories.UpdateLabel(&label); err != nil {
+		log.Println("Error updating label:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update label"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"label": label})
+}
+
+// DeleteLabel deletes a catalog label and removes it from every card.
+func DeleteLabel(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+	labelID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid label ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to delete label"})
+	}
+
+	var label models.Label
+	if err := repositories.GetLabelByID(uint(labelID), &label); err != nil || label.WorkspaceID != uint(workspaceID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Label not found"})
+	}
+
+	if err := repositories.DeleteLabel(label.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete label"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Label deleted successfully"})
+}
+
+// createCatalogLabel validates and creates a label in a workspace's catalog.
+// An empty color falls back to models.DefaultLabelColor.
+func createCatalogLabel(workspaceID uint, name, color string) (*models.Label, *fiber.Error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Name is required")
+	}
+	if err := utils.ValidateLength("Name", name, utils.MaxLabelNameLength); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	color = strings.TrimSpace(color)
+	if color == "" {
+		color = models.DefaultLabelColor
+	}
+	if !utils.IsValidHexColor(color) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Color must be a hex code like #FF0000")
+	}
+
+	taken, err := repositories.IsLabelNameTaken(workspaceID, name, 0)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to check label name")
+	}
+	if taken {
+		return nil, fiber.NewError(fiber.StatusConflict, "A label with this name already exists")
+	}
+
+	label := models.Label{
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Color:       strings.ToUpper(color),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := repositories.CreateLabel(&label); err != nil {
+		log.Println("Error creating label:", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to create label")
+	}
+	return &label, nil
+}
+
+// resolveCatalogLabel finds the catalog label a card label request refers to: by "label_id" if given,
+// otherwise by "name", creating the label in the workspace catalog if it does not exist yet.
+func resolveCatalogLabel(c *fiber.Ctx, workspaceID uint) (*models.Label, *fiber.Error) {
+	if labelIDStr := c.FormValue("label_id"); labelIDStr != "" {
+		labelID, err := strconv.Atoi(labelIDStr)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid label_id")
+		}
+		var label models.Label
+		if err := repositories.GetLabelByID(uint(labelID), &label); err != nil || label.WorkspaceID != workspaceID {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Label not found in this workspace")
+		}
+		return &label, nil
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "label_id or name is required")
+	}
+
+	var label models.Label
+	if err := repositories.GetLabelByName(workspaceID, name, &label); err == nil {
+		return &label, nil
+	}
+	return createCatalogLabel(workspaceID, name, c.FormValue("color"))
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"time"
+
+	"kelarin-backend/dto"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMyCards returns every card assigned to the authenticated user across all accessible workspaces.
+// Query parameters (all optional):
+//   - tz: IANA time zone used to compute "today" and "this week" (default UTC)
+//   - bucket: only return cards in this deadline bucket
+//     ("overdue", "today", "this_week", "later" or "no_deadline")
+//   - group: "true" to return the cards grouped by deadline bucket
+func GetMyCards(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	loc, err := time.LoadLocation(c.Query("tz", "UTC"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid time zone"})
+	}
+
+	bucket := c.Query("bucket")
+	if bucket != "" && !isDeadlineBucket(bucket) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "bucket must be overdue, today, this_week, later or no_deadline"})
+	}
+
+	var cards []models.Card
+	if err := repositories.GetCardsAssignedToUser(userID, &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch assigned cards"})
+	}
+
+	now := time.Now().In(loc)
+	response := make([]dto.CardSummaryResponse, 0, len(cards))
+	counts := make(map[string]int, len(utils.DeadlineBuckets))
+	for _, b := range utils.DeadlineBuckets {
+		counts[b] = 0
+	}
+	for _, card := range cards {
+		cardBucket := utils.DeadlineBucket(card.Deadline, now)
+		counts[cardBucket]++
+		if bucket != "" && cardBucket != bucket {
+			continue
+		}
+		response = append(response, dto.NewCardSummaryResponse(&card, cardBucket))
+	}
+
+	if c.QueryBool("group", false) {
+		groups := make(map[string][]dto.CardSummaryResponse, len(utils.DeadlineBuckets))
+		for _, b := range utils.DeadlineBuckets {
+			if bucket == "" || b == bucket {
+				groups[b] = make([]dto.CardSummaryResponse, 0)
+			}
+		}
+		for _, card := range response {
+			groups[card.DeadlineBucket] = append(groups[card.DeadlineBucket], card)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"groups": groups, "counts": counts})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cards": response, "counts": counts})
+}
+
+// isDeadlineBucket reports whether bucket is one of utils.DeadlineBuckets.
+func isDeadlineBucket(bucket string) bool {
+	for _, b := range utils.DeadlineBuckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
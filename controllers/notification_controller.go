@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// parsePagination reads the "page" and "limit" query parameters, applying defaults and a maximum limit.
+func parsePagination(c *fiber.Ctx) (page, limit int) {
+	page = c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit = c.QueryInt("limit", 20)
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
+
+// GetNotifications returns the authenticated user's notifications, newest first, with the unread count.
+// Supports "page", "limit" and "unread=true" query parameters.
+func GetNotifications(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	page, limit := parsePagination(c)
+	unreadOnly := c.QueryBool("unread", false)
+
+	var notifications []models.Notification
+	total, err := repositories.GetNotificationsByUser(userID, unreadOnly, limit, (page-1)*limit, &notifications)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notifications"})
+	}
+
+	unreadCount, err := repositories.CountUnreadNotifications(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to count unread notifications"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"notifications": notifications,
+		"unread_count":  unreadCount,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+// MarkNotificationRead marks one of the authenticated user's notifications as read.
+func MarkNotificationRead(c *fiber.Ctx) error {
+	notificationID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid notification ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var notification models.Notification
+	if err := repositories.GetNotificationByID(uint(notificationID), &notification); err != nil || notification.UserID != userID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notification not found"})
+	}
+
+	if err := repositories.MarkNotificationRead(notification.ID, userID, time.Now()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to mark notification as read"})
+	}
+
+	if err := repositories.GetNotificationByID(notification.ID, &notification); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load notification"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"notification": notification})
+}
+
+// MarkAllNotificationsRead marks all of the authenticated user's notifications as read.
+func MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	updated, err := repositories.MarkAllNotificationsRead(userID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to mark notifications as read"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "All notifications marked as read",
+		"updated": updated,
+	})
+}
+
+// GetNotificationPreferences returns whether each notification type is enabled for the authenticated user.
+func GetNotificationPreferences(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	preferences, err := loadNotificationPreferences(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notification preferences"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"preferences": preferences})
+}
+
+// UpdateNotificationPreferences enables or disables notification types for the authenticated user.
+// Expects form-data with a "true" or "false" value per notification type (e.g., card_assigned=false).
+// Types that are not provided are left unchanged.
+func UpdateNotificationPreferences(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	for _, notificationType := range models.NotificationTypes {
+		value := c.FormValue(notificationType)
+		if value == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid value for " + notificationType})
+		}
+
+		preference := models.NotificationPreference{
+			UserID:  userID,
+			Type:    notificationType,
+			Enabled: enabled,
+		}
+		if err := repositories.SaveNotificationPreference(&preference); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update notification preferences"})
+		}
+	}
+
+	preferences, err := loadNotificationPreferences(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notification preferences"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"preferences": preferences})
+}
+
+// loadNotificationPreferences returns a map of every notification type to whether it is enabled for a user.
+func loadNotificationPreferences(userID uint) (map[string]bool, error) {
+	var stored []models.NotificationPreference
+	if err := repositories.GetNotificationPreferences(userID, &stored); err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+// GetEmailDigestSetting returns the authenticated user's email digest setting.
+func GetEmailDigestSetting(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	setting := models.EmailDigestSetting{UserID: userID, Frequency: models.DigestFrequencyNone}
+	if err := repositories.GetEmailDigestSetting(userID, &setting); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch email digest setting"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"digest": setting})
+}
+
+// UpdateEmailDigestSetting opts the authenticated user in or out of the email digest.
+// Expects form-data "frequency" set to "none", "daily" or "weekly".
+func UpdateEmailDigestSetting(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	frequency := c.FormValue("frequency")
+	if frequency != models.DigestFrequencyNone && frequency != models.DigestFrequencyDaily && frequency != models.DigestFrequencyWeekly {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Frequency must be none, daily or weekly"})
+	}
+
+	setting := models.EmailDigestSetting{UserID: userID}
+	if err := repositories.GetEmailDigestSetting(userID, &setting); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch email digest setting"})
+	}
+
+	setting.Frequency = frequency
+	setting.UpdatedAt = time.Now()
+	if err := repositories.SaveEmailDigestSetting(&setting); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update email digest setting"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"digest": setting})
+}
+
+// PreviewEmailDigest renders the authenticated user's digest as HTML without sending it.
+// The optional "frequency" query parameter selects a daily (default) or weekly digest.
+func PreviewEmailDigest(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	frequency := c.Query("frequency", models.DigestFrequencyDaily)
+	period, err := utils.DigestPeriod(frequency)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Frequency must be daily or weekly"})
+	}
+
+	user, err := repositories.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	}
+
+	now := time.Now()
+	data, err := utils.BuildDigest(user, frequency, now.Add(-period), now)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build email digest"})
+	}
+
+	msg, err := utils.RenderDigest(data)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to render email digest"})
+	}
+
+	c.Type("html", "utf-8")
+	return c.Status(fiber.StatusOK).SendString(msg.HTML)
+}
@@ -4,6 +4,7 @@ import (
 	"kelarin-backend/utils"
 	"log"
 	"strconv"
+	"time"
 
 	"kelarin-backend/models"
 	"kelarin-backend/repositories"
@@ -11,7 +12,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// CreateSubtask creates a new subtask for a given card.
+// CreateSubtask creates a new subtask for a given card, at the end of its checklist.
+// Expects form-data "title", and optionally "checklist_id", "assignee_id", "due_date" (RFC3339)
+// and "position".
 func CreateSubtask(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("card_id"))
 	if err != nil {
@@ -27,6 +30,9 @@ func CreateSubtask(c *fiber.Ctx) error {
 		Title:  title,
 		CardID: uint(cardID),
 	}
+	if _, ferr := applySubtaskFields(c, &subtask, true); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
 
 	if err := repositories.CreateSubtask(&subtask); err != nil {
 		log.Println("Error creating subtask:", err)
@@ -37,12 +43,14 @@ func CreateSubtask(c *fiber.Ctx) error {
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
+	undoToken := recordUndo(userID, utils.UndoEntitySubtask, utils.UndoActionCreate, utils.UndoKey{ID: subtask.ID}, "")
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask})
+	c.Set(fiber.HeaderETag, utils.ETag(subtask.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask, "undo_token": undoToken})
 }
 
 // GetSubtasks retrieves all subtasks for a given card.
@@ -72,10 +80,13 @@ func GetSubtask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
 	}
 
+	c.Set(fiber.HeaderETag, utils.ETag(subtask.Version))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask})
 }
 
-// UpdateSubtask updates an existing subtask.
+// UpdateSubtask updates an existing subtask. Only the fields that are sent are changed.
+// Send the subtask's ETag in If-Match to reject the update with 409 Conflict if the subtask has
+// been changed since it was loaded.
 func UpdateSubtask(c *fiber.Ctx) error {
 	subtaskID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -87,28 +98,64 @@ func UpdateSubtask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
 	}
 
-	subtask.Title = c.FormValue("title")
+	if !utils.IfMatchSatisfied(c.Get(fiber.HeaderIfMatch), subtask.Version) {
+		return respondVersionConflict(c, "subtask", subtask, subtask.Version)
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	before := captureUndo(utils.UndoEntitySubtask, utils.UndoKey{ID: subtask.ID})
+
+	wasDone := subtask.IsDone
+	var columns []string
+	if title, ok := lookupFormValue(c, "title"); ok {
+		subtask.Title = title
+		columns = append(columns, "title")
+	}
 	isDoneStr := c.FormValue("is_done")
 	if isDoneStr == "true" {
 		subtask.IsDone = true
+		columns = append(columns, "is_done")
 	} else if isDoneStr == "false" {
 		subtask.IsDone = false
+		columns = append(columns, "is_done")
+	}
+	fieldColumns, ferr := applySubtaskFields(c, &subtask, false)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
 	}
+	columns = append(columns, fieldColumns...)
 
-	if err := repositories.UpdateSubtask(&subtask); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update subtask"})
+	if len(columns) == 0 {
+		c.Set(fiber.HeaderETag, utils.ETag(subtask.Version))
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask})
 	}
 
-	userID, ok := c.Locals("user_id").(uint)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	updated, err := repositories.UpdateSubtask(&subtask, columns)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update subtask"})
+	}
+	if !updated {
+		var current models.Subtask
+		if err := repositories.GetSubtaskByID(subtask.ID, &current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subtask not found"})
+		}
+		return respondVersionConflict(c, "subtask", current, current.Version)
+	}
+	undoToken := recordUndo(userID, utils.UndoEntitySubtask, utils.UndoActionUpdate, utils.UndoKey{ID: subtask.ID}, before)
+	if !wasDone && subtask.IsDone {
+		utils.DispatchSubtaskDone(subtask.CardID, userID)
 	}
 
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask})
+	c.Set(fiber.HeaderETag, utils.ETag(subtask.Version))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"subtask": subtask, "undo_token": undoToken})
 }
 
 // DeleteSubtask deletes a subtask by its ID.
@@ -118,18 +165,97 @@ func DeleteSubtask(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid subtask ID"})
 	}
 
-	if err := repositories.DeleteSubtask(uint(subtaskID)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete subtask"})
-	}
-
 	userID, ok := c.Locals("user_id").(uint)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	before := captureUndo(utils.UndoEntitySubtask, utils.UndoKey{ID: uint(subtaskID)})
+	if err := repositories.DeleteSubtask(uint(subtaskID)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete subtask"})
+	}
+	undoToken := recordUndo(userID, utils.UndoEntitySubtask, utils.UndoActionDelete, utils.UndoKey{ID: uint(subtaskID)}, before)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Subtask deleted successfully"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Subtask deleted successfully", "undo_token": undoToken})
+}
+
+// applySubtaskFields reads the optional "checklist_id", "assignee_id", "due_date" and "position"
+// form fields into a subtask and returns the columns they changed; an empty value clears the
+// field. The checklist must be on the subtask's card and the assignee a member of its workspace.
+// A subtask that is created or moved to another checklist without a position goes to the end.
+func applySubtaskFields(c *fiber.Ctx, subtask *models.Subtask, creating bool) ([]string, *fiber.Error) {
+	var columns []string
+	placeAtEnd := creating
+	if raw, ok := lookupFormValue(c, "checklist_id"); ok {
+		var checklistID *uint
+		if raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid checklist_id")
+			}
+			var checklist models.Checklist
+			if err := repositories.GetChecklistByID(uint(id), &checklist); err != nil || checklist.CardID != subtask.CardID {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "checklist_id must be a checklist of the same card")
+			}
+			checklistID = &checklist.ID
+		}
+		if (checklistID == nil) != (subtask.ChecklistID == nil) || (checklistID != nil && *checklistID != *subtask.ChecklistID) {
+			placeAtEnd = true
+		}
+		subtask.ChecklistID = checklistID
+		columns = append(columns, "checklist_id")
+	}
+
+	if raw, ok := lookupFormValue(c, "assignee_id"); ok {
+		subtask.AssigneeID = nil
+		if raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid assignee_id")
+			}
+			var card models.Card
+			if err := repositories.GetCardWithListByID(subtask.CardID, &card); err != nil {
+				return nil, fiber.NewError(fiber.StatusNotFound, "Card not found")
+			}
+			if _, err := utils.CheckRoleInWorkspace(uint(id), card.List.WorkspaceID); err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "assignee_id must be a member of the workspace")
+			}
+			assigneeID := uint(id)
+			subtask.AssigneeID = &assigneeID
+		}
+		columns = append(columns, "assignee_id")
+	}
+
+	if raw, ok := lookupFormValue(c, "due_date"); ok {
+		subtask.DueDate = nil
+		if raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid due_date format")
+			}
+			subtask.DueDate = &parsed
+		}
+		columns = append(columns, "due_date")
+	}
+
+	if raw, ok := lookupFormValue(c, "position"); ok && raw != "" {
+		position, err := strconv.Atoi(raw)
+		if err != nil || position < 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "position must be a non-negative number")
+		}
+		subtask.Position = position
+		columns = append(columns, "position")
+	} else if placeAtEnd {
+		position, err := repositories.NextSubtaskPosition(subtask.CardID, subtask.ChecklistID)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to position subtask")
+		}
+		subtask.Position = position
+		columns = append(columns, "position")
+	}
+	return columns, nil
 }
@@ -0,0 +1,289 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"kelarin-backend/dto"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// maxManualTimeEntryMinutes is the longest duration a single manual time entry may log.
+const maxManualTimeEntryMinutes = 24 * 60
+
+// StartTimer starts a timer for the authenticated user on a card. Any timer the user already
+// has running, on this or another card, is stopped first.
+// Accepts optional form-data "note".
+func StartTimer(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadCardForTimeTracking(c, userID, true)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	entry := models.TimeEntry{
+		CardID:    card.ID,
+		UserID:    userID,
+		StartedAt: time.Now(),
+		Note:      c.FormValue("note"),
+		CreatedAt: time.Now(),
+	}
+
+	if err := repositories.StartTimer(&entry); err != nil {
+		log.Println("Error starting timer:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start timer"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"time_entry": entry})
+}
+
+// StopTimer stops the authenticated user's running timer on a card.
+func StopTimer(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadCardForTimeTracking(c, userID, true)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var entry models.TimeEntry
+	if err := repositories.GetRunningTimeEntry(card.ID, userID, &entry); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No timer is running on this card"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch running timer"})
+	}
+
+	now := time.Now()
+	entry.EndedAt = &now
+	entry.DurationSeconds = int64(now.Sub(entry.StartedAt).Seconds())
+
+	if err := repositories.UpdateTimeEntry(&entry); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to stop timer"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"time_entry": entry})
+}
+
+// CreateTimeEntry logs time on a card manually.
+// Expects form-data "duration_minutes" (at most 24 hours), and optional "started_at" (RFC3339,
+// not in the future, defaults to the duration before now) and "note".
+func CreateTimeEntry(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadCardForTimeTracking(c, userID, true)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	minutes, err := strconv.Atoi(c.FormValue("duration_minutes"))
+	if err != nil || minutes <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "duration_minutes must be a positive number"})
+	}
+	if minutes > maxManualTimeEntryMinutes {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "duration_minutes cannot exceed 24 hours"})
+	}
+	duration := time.Duration(minutes) * time.Minute
+
+	startedAt := time.Now().Add(-duration)
+	if raw := c.FormValue("started_at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid started_at format"})
+		}
+		if parsed.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "started_at cannot be in the future"})
+		}
+		startedAt = parsed
+	}
+	endedAt := startedAt.Add(duration)
+
+	entry := models.TimeEntry{
+		CardID:          card.ID,
+		UserID:          userID,
+		StartedAt:       startedAt,
+		EndedAt:         &endedAt,
+		DurationSeconds: int64(duration.Seconds()),
+		Note:            c.FormValue("note"),
+		Manual:          true,
+		CreatedAt:       time.Now(),
+	}
+
+	if err := repositories.CreateTimeEntry(&entry); err != nil {
+		log.Println("Error creating time entry:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to log time"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"time_entry": entry})
+}
+
+// GetTimeEntries returns the time entries of a card with the total time tracked on it,
+// overall and per user. Running timers are counted up to now.
+func GetTimeEntries(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	card, ferr := loadCardForTimeTracking(c, userID, false)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	var entries []models.TimeEntry
+	if err := repositories.GetTimeEntriesByCard(card.ID, &entries); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch time entries"})
+	}
+
+	now := time.Now()
+	var total int64
+	perUser := make(map[uint]int64)
+	var userOrder []uint
+	response := make([]dto.TimeEntryResponse, 0, len(entries))
+	for i := range entries {
+		entry := dto.NewTimeEntryResponse(&entries[i], now)
+		response = append(response, entry)
+
+		total += entry.DurationSeconds
+		if _, seen := perUser[entries[i].UserID]; !seen {
+			userOrder = append(userOrder, entries[i].UserID)
+		}
+		perUser[entries[i].UserID] += entry.DurationSeconds
+	}
+
+	byUser := make([]dto.TimeTotalResponse, 0, len(userOrder))
+	for _, id := range userOrder {
+		byUser = append(byUser, dto.TimeTotalResponse{UserID: id, Seconds: perUser[id]})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"time_entries":  response,
+		"total_seconds": total,
+		"by_user":       byUser,
+	})
+}
+
+// DeleteTimeEntry deletes one of the authenticated user's time entries.
+func DeleteTimeEntry(c *fiber.Ctx) error {
+	entryID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid time entry ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var entry models.TimeEntry
+	if err := repositories.GetTimeEntryByID(uint(entryID), &entry); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Time entry not found"})
+	}
+	if entry.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only delete your own time entries"})
+	}
+
+	if err := repositories.DeleteTimeEntry(entry.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete time entry"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Time entry deleted successfully"})
+}
+
+// GetWorkspaceTimeTracking returns the time tracked in a workspace per card and per user.
+// Accepts optional "from" and "to" RFC3339 query parameters bounding when entries started.
+func GetWorkspaceTimeTracking(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	from, err := parseOptionalTime(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from format"})
+	}
+	to, err := parseOptionalTime(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to format"})
+	}
+
+	byCard, byUser, err := repositories.GetWorkspaceTimeTotals(uint(workspaceID), from, to, time.Now())
+	if err != nil {
+		log.Println("Error fetching time totals:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch time tracking"})
+	}
+
+	var total int64
+	cards := make([]dto.TimeTotalResponse, 0, len(byCard))
+	for _, t := range byCard {
+		total += t.Seconds
+		cards = append(cards, dto.TimeTotalResponse{CardID: t.ID, Seconds: t.Seconds})
+	}
+	users := make([]dto.TimeTotalResponse, 0, len(byUser))
+	for _, t := range byUser {
+		users = append(users, dto.TimeTotalResponse{UserID: t.ID, Seconds: t.Seconds})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"total_seconds": total,
+		"by_card":       cards,
+		"by_user":       users,
+	})
+}
+
+// loadCardForTimeTracking loads the card in the "card_id" route parameter and checks that the
+// user is a member of its workspace, and an editor or above when write is set.
+func loadCardForTimeTracking(c *fiber.Ctx, userID uint, write bool) (*models.Card, *fiber.Error) {
+	cardID, err := strconv.Atoi(c.Params("card_id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid card ID")
+	}
+
+	var card models.Card
+	if err := repositories.GetCardWithListByID(uint(cardID), &card); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Card not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusForbidden, "You do not have access to this workspace")
+	}
+	if write && !utils.IsEditorAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to track time on this card")
+	}
+	return &card, nil
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"kelarin-backend/dto"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetDeletedWorkspaces returns the authenticated user's own workspaces that are in the trash.
+func GetDeletedWorkspaces(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var workspaces []models.Workspace
+	if err := repositories.GetDeletedWorkspacesByOwner(userID, &workspaces); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch deleted workspaces"})
+	}
+
+	retention := utils.TrashRetention()
+	response := make([]dto.TrashedWorkspaceResponse, 0, len(workspaces))
+	for i := range workspaces {
+		response = append(response, dto.NewTrashedWorkspaceResponse(&workspaces[i], retention))
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"workspaces": response})
+}
+
+// RestoreWorkspace brings one of the authenticated user's workspaces back from the trash,
+// together with the lists and cards deleted with it.
+func RestoreWorkspace(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var workspace models.Workspace
+	if err := repositories.GetDeletedWorkspaceByID(uint(workspaceID), &workspace); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found in trash"})
+	}
+	if workspace.OwnerID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only the owner can restore a workspace"})
+	}
+
+	if err := repositories.RestoreWorkspace(&workspace); err != nil {
+		log.Println("Error restoring workspace:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore workspace"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Workspace restored successfully"})
+}
+
+// GetWorkspaceTrash returns the lists and cards of a workspace that are in the trash.
+// Cards deleted together with their list are counted on the list rather than listed.
+func GetWorkspaceTrash(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	var lists []repositories.TrashedList
+	if err := repositories.GetTrashedListsByWorkspace(uint(workspaceID), &lists); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch deleted lists"})
+	}
+
+	var cards []repositories.TrashedCard
+	if err := repositories.GetTrashedCardsByWorkspace(uint(workspaceID), &cards); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch deleted cards"})
+	}
+
+	retention := utils.TrashRetention()
+	listResponse := make([]dto.TrashedListResponse, 0, len(lists))
+	for i := range lists {
+		listResponse = append(listResponse, dto.NewTrashedListResponse(&lists[i], retention))
+	}
+	cardResponse := make([]dto.TrashedCardResponse, 0, len(cards))
+	for i := range cards {
+		cardResponse = append(cardResponse, dto.NewTrashedCardResponse(&cards[i], retention))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"lists": listResponse, "cards": cardResponse})
+}
+
+// RestoreBoardList brings a board list back from the trash together with the cards deleted with it.
+func RestoreBoardList(c *fiber.Ctx) error {
+	listID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid list ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var list models.BoardList
+	if err := repositories.GetDeletedBoardListByID(uint(listID), &list); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "List not found in trash"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, list.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to restore board list"})
+	}
+
+	if err := repositories.RestoreBoardList(&list); err != nil {
+		if errors.Is(err, repositories.ErrParentDeleted) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Restore the workspace first"})
+		}
+		log.Println("Error restoring board list:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore board list"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	var restored models.BoardList
+	if err := repositories.GetBoardListByID(list.ID, &restored); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch restored board list"})
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"list": restored})
+}
+
+// RestoreCard brings a card back from the trash into its list.
+func RestoreCard(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid card ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var card models.Card
+	if err := repositories.GetDeletedCardWithListByID(uint(cardID), &card); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Card not found in trash"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, card.List.WorkspaceID)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+	if !utils.IsEditorAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to restore card"})
+	}
+
+	warning := ""
+	if card.ArchivedAt == nil && !card.List.DeletedAt.Valid {
+		var ferr *fiber.Error
+		if warning, ferr = checkListWIPLimit(card.ListID, 1); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+	}
+
+	if err := repositories.RestoreCard(&card); err != nil {
+		if errors.Is(err, repositories.ErrParentDeleted) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Restore the list first"})
+		}
+		if errors.Is(err, utils.ErrWIPLimitReached) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Println("Error restoring card:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to restore card"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	var restored models.Card
+	if err := repositories.GetCardByID(card.ID, &restored); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch restored card"})
+	}
+	response := fiber.Map{"card": restored}
+	if warning != "" {
+		response["warning"] = warning
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
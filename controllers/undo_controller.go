@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UndoOperation reverses the kanban operation that returned the "token" route parameter as
+// its undo_token, as long as the undo window has not passed and the item has not changed since.
+func UndoOperation(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	undo, err := utils.Undo(c.Params("token"), userID, time.Now())
+	switch {
+	case errors.Is(err, utils.ErrUndoNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Undo token not found"})
+	case errors.Is(err, utils.ErrUndoExpired):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "This operation can no longer be undone"})
+	case errors.Is(err, utils.ErrUndoConflict):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The item has changed since this operation and cannot be undone"})
+	case errors.Is(err, utils.ErrWIPLimitReached):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		log.Println("Error undoing operation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to undo operation"})
+	}
+
+	if err := utils.IncrementStreak(userID); err != nil {
+		log.Println("Error incrementing streak:", err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Operation undone successfully",
+		"entity":  undo.Entity,
+		"action":  undo.Action,
+	})
+}
+
+// captureUndo snapshots a row before an operation for recordUndo. Failures are logged and
+// only mean the operation cannot be undone.
+func captureUndo(entity string, key utils.UndoKey) string {
+	before, err := utils.CaptureUndoSnapshot(entity, key)
+	if err != nil {
+		log.Println("Error capturing undo snapshot:", err)
+	}
+	return before
+}
+
+// recordUndo records an operation the user has just performed and returns its undo token,
+// or an empty string if it could not be recorded.
+func recordUndo(userID uint, entity, action string, key utils.UndoKey, before string) string {
+	token, err := utils.RecordUndo(userID, entity, action, key, before)
+	if err != nil {
+		log.Println("Error recording undo action:", err)
+		return ""
+	}
+	return token
+}
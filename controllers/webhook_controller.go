@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+	"kelarin-backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateWebhook subscribes a URL to events of a workspace. Only admins and owners can manage
+// webhooks. Expects form-data:
+//   - url: the http or https URL to POST events to
+//   - events: comma-separated event types (card.created, card.moved, card.updated,
+//     comment.added, member.shared)
+//   - format (optional): "json" (default) for the signed event payload, or "slack" or "discord"
+//     to post the events as chat messages to an incoming-webhook URL
+//   - enabled (optional, default true)
+//
+// The signing secret is only returned here and when it is rotated.
+func CreateWebhook(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Failed to retrieve user role in workspace"})
+	}
+	if !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to create webhook"})
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate webhook secret"})
+	}
+
+	webhook := models.Webhook{
+		WorkspaceID: uint(workspaceID),
+		Secret:      secret,
+		Format:      models.WebhookFormatJSON,
+		Enabled:     true,
+		CreatedByID: userID,
+	}
+	if ferr := applyWebhookForm(c, &webhook); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.CreateWebhook(&webhook); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create webhook"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"webhook": webhook, "secret": secret})
+}
+
+// GetWebhooks returns the webhooks of a workspace.
+func GetWebhooks(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("workspace_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID))
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+	if !utils.IsAdminOwner(role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permission to view webhooks"})
+	}
+
+	var webhooks []models.Webhook
+	if err := repositories.GetWebhooksByWorkspace(uint(workspaceID), &webhooks); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch webhooks"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"webhooks": webhooks})
+}
+
+// UpdateWebhook updates a webhook. It accepts the same form-data as CreateWebhook; only the
+// fields that are sent are changed. With "rotate_secret=true" a new signing secret is generated
+// and returned.
+func UpdateWebhook(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	webhook, ferr := loadManagedWebhook(c, userID, "update")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if ferr := applyWebhookForm(c, webhook); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	response := fiber.Map{"webhook": webhook}
+	if c.FormValue("rotate_secret") == "true" {
+		secret, err := utils.GenerateSecureToken(32)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate webhook secret"})
+		}
+		webhook.Secret = secret
+		response["secret"] = secret
+	}
+
+	if err := repositories.UpdateWebhook(webhook); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update webhook"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// DeleteWebhook deletes a webhook together with its delivery log and queued deliveries.
+func DeleteWebhook(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	webhook, ferr := loadManagedWebhook(c, userID, "delete")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	if err := repositories.DeleteWebhook(webhook.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete webhook"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first, including the
+// ones still waiting to be retried. Query parameter "limit" defaults to 50 (max 200).
+func GetWebhookDeliveries(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	webhook, ferr := loadManagedWebhook(c, userID, "view")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := repositories.GetWebhookDeliveries(webhook.ID, limit, &deliveries); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch webhook deliveries"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"deliveries": deliveries})
+}
+
+// TestWebhook sends a signed "ping" event to a webhook right away and returns the delivery,
+// including the receiver's response. Test events are not retried.
+func TestWebhook(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	webhook, ferr := loadManagedWebhook(c, userID, "test")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
+
+	delivery, err := utils.SendTestWebhook(webhook)
+	if err != nil {
+		log.Println("Error sending test webhook:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send test event"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"delivery": delivery})
+}
+
+// loadManagedWebhook loads the webhook in the "id" route parameter and checks that the user is
+// an admin or owner of its workspace.
+func loadManagedWebhook(c *fiber.Ctx, userID uint, action string) (*models.Webhook, *fiber.Error) {
+	webhookID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	var webhook models.Webhook
+	if err := repositories.GetWebhookByID(uint(webhookID), &webhook); err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+	}
+
+	role, err := utils.CheckRoleInWorkspace(userID, webhook.WorkspaceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Failed to retrieve user role in workspace")
+	}
+	if !utils.IsAdminOwner(role) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Insufficient permission to "+action+" webhook")
+	}
+	return &webhook, nil
+}
+
+// applyWebhookForm applies the webhook fields present in the form to a webhook and validates
+// the result.
+func applyWebhookForm(c *fiber.Ctx, webhook *models.Webhook) *fiber.Error {
+	if raw, ok := lookupFormValue(c, "url"); ok {
+		webhook.URL = strings.TrimSpace(raw)
+	}
+	if err := utils.ValidateWebhookURL(webhook.URL); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if raw, ok := lookupFormValue(c, "events"); ok {
+		events := models.WebhookEvents{}
+		seen := make(map[string]bool)
+		for _, event := range strings.Split(raw, ",") {
+			event = strings.TrimSpace(event)
+			if event == "" || seen[event] {
+				continue
+			}
+			if !utils.IsValidWebhookEvent(event) {
+				return fiber.NewError(fiber.StatusBadRequest, "Unknown webhook event "+strconv.Quote(event))
+			}
+			seen[event] = true
+			events = append(events, event)
+		}
+		webhook.Events = events
+	}
+	if len(webhook.Events) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "events must list at least one event type")
+	}
+
+	if format, ok := lookupFormValue(c, "format"); ok {
+		if !utils.IsValidWebhookFormat(format) {
+			return fiber.NewError(fiber.StatusBadRequest, "format must be json, slack or discord")
+		}
+		webhook.Format = format
+	}
+
+	if raw, ok := lookupFormValue(c, "enabled"); ok {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid enabled value")
+		}
+		webhook.Enabled = enabled
+	}
+	return nil
+}
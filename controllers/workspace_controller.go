@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -207,6 +208,7 @@ func GetWorkspace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
 	}
 	response := dto.NewWorkspaceResponse(&ws)
+	c.Set(fiber.HeaderETag, utils.ETag(ws.Version))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"workspace": response})
 }
 
@@ -226,13 +228,13 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	// Bind basic fields using DTO
-	var updateReq dto.UpdateWorkspaceRequest
-	updateReq.Title = c.FormValue("title")
-	updateReq.Purpose = c.FormValue("purpose")
-	updateReq.Description = c.FormValue("description")
+	// Reject the update if the workspace changed since the client loaded it
+	if !utils.IfMatchSatisfied(c.Get(fiber.HeaderIfMatch), ws.Version) {
+		return respondVersionConflict(c, "workspace", dto.NewWorkspaceResponse(&ws), ws.Version)
+	}
 
 	// Parse collaborator changes from JSON strings in form-data
+	var updateReq dto.UpdateWorkspaceRequest
 	if formVal := c.FormValue("add_collaborators"); formVal != "" {
 		if err := json.Unmarshal([]byte(formVal), &updateReq.AddCollaborators); err != nil {
 			log.Println("Error parsing add_collaborators:", err)
@@ -249,15 +251,22 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 		}
 	}
 
-	// Update basic fields if provided
-	if updateReq.Title != "" {
-		ws.Title = updateReq.Title
+	// Update the basic fields that were sent; purpose and description may be cleared
+	var columns []string
+	if title, ok := lookupFormValue(c, "title"); ok {
+		if strings.TrimSpace(title) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Title is required"})
+		}
+		ws.Title = title
+		columns = append(columns, "title")
 	}
-	if updateReq.Purpose != "" {
-		ws.Purpose = updateReq.Purpose
+	if purpose, ok := lookupFormValue(c, "purpose"); ok {
+		ws.Purpose = purpose
+		columns = append(columns, "purpose")
 	}
-	if updateReq.Description != "" {
-		ws.Description = updateReq.Description
+	if description, ok := lookupFormValue(c, "description"); ok {
+		ws.Description = description
+		columns = append(columns, "description")
 	}
 
 	// Prepare upload directory
@@ -276,6 +285,7 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save workspace_picture"})
 		}
 		ws.WorkspacePicture = picPath
+		columns = append(columns, "workspace_picture")
 	}
 
 	// Update workspace_banner if file is provided
@@ -288,13 +298,21 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save workspace_banner"})
 		}
 		ws.WorkspaceBanner = bannerPath
+		columns = append(columns, "workspace_banner")
 	}
 
-	// Update the updated time and save basic changes
-	ws.UpdatedAt = time.Now()
-	if err := database.DB.Save(&ws).Error; err != nil {
+	// Save basic changes; this also bumps the version, as collaborator changes are part of the workspace
+	updated, err := repositories.UpdateWorkspace(&ws, columns)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update workspace"})
 	}
+	if !updated {
+		var current models.Workspace
+		if err := repositories.GetWorkspaceByIDWithOwner(id, &current); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Workspace not found"})
+		}
+		return respondVersionConflict(c, "workspace", dto.NewWorkspaceResponse(&current), current.Version)
+	}
 
 	// ---- Handling Collaborator Changes ----
 
@@ -310,7 +328,12 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 		}
 		if err := repositories.AddCollaboratorToWorkspaceWithRole(&ws, user, collab.Role); err != nil {
 			log.Println("Failed to add collaborator:", collab.Email, err)
+			continue
 		}
+		if err := utils.NotifyWorkspaceShared(&ws, user.ID, collab.Role, userID); err != nil {
+			log.Println("Failed to notify collaborator:", collab.Email, err)
+		}
+		utils.EmitMemberSharedWebhookEvent(ws.ID, user, collab.Role, userID)
 	}
 
 	// 2. Remove collaborators
@@ -351,6 +374,7 @@ func UpdateWorkspace(c *fiber.Ctx) error {
 	}
 
 	response := dto.NewWorkspaceResponse(&ws)
+	c.Set(fiber.HeaderETag, utils.ETag(ws.Version))
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message":   "Workspace updated successfully",
 		"workspace": response,
@@ -413,6 +437,11 @@ func ShareWorkspace(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
+	if err := utils.NotifyWorkspaceShared(&ws, user.ID, payload.Role, userID); err != nil {
+		log.Println("Error notifying shared user:", err)
+	}
+	utils.EmitMemberSharedWebhookEvent(ws.ID, user, payload.Role, userID)
+
 	if err := utils.IncrementStreak(userID); err != nil {
 		log.Println("Error incrementing streak:", err)
 	}
@@ -444,3 +473,49 @@ func DeleteWorkspace(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Workspace deleted successfully"})
 }
+
+// GetMentionCandidates returns workspace members matching the "q" query parameter,
+// with the handle to use when @mentioning them in a comment.
+func GetMentionCandidates(c *fiber.Ctx) error {
+	workspaceID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid workspace ID"})
+	}
+
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if _, err := utils.CheckRoleInWorkspace(userID, uint(workspaceID)); err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You do not have access to this workspace"})
+	}
+
+	query := strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@")
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	var matches []models.User
+	if err := repositories.SearchWorkspaceMembers(uint(workspaceID), query, limit, &matches); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to search workspace members"})
+	}
+
+	// Handles depend on every member, not only the matches, to detect shared email prefixes.
+	var members []models.User
+	if err := repositories.GetWorkspaceMembers(uint(workspaceID), &members); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch workspace members"})
+	}
+
+	response := make([]dto.MentionCandidateResponse, len(matches))
+	for i, u := range matches {
+		response[i] = dto.MentionCandidateResponse{
+			ID:       u.ID,
+			FullName: u.FullName,
+			Email:    u.Email,
+			Handle:   utils.MentionHandle(&u, members),
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"members": response})
+}
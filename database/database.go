@@ -3,8 +3,8 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
 
+	"kelarin-backend/env"
 	"kelarin-backend/models"
 
 	"github.com/joho/godotenv"
@@ -23,11 +23,11 @@ func ConnectDatabase() {
 
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		getEnv("DB_HOST", "localhost"),
-		getEnv("DB_USER", "postgres"),
-		getEnv("DB_PASSWORD", "password"),
-		getEnv("DB_NAME", "db"),
-		getEnv("DB_PORT", "5432"),
+		env.Get("DB_HOST", "localhost"),
+		env.Get("DB_USER", "postgres"),
+		env.Get("DB_PASSWORD", "password"),
+		env.Get("DB_NAME", "db"),
+		env.Get("DB_PORT", "5432"),
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
@@ -41,16 +41,42 @@ func ConnectDatabase() {
 		&models.WorkspaceUser{},
 		&models.BoardList{},
 		&models.Card{},
+		&models.Checklist{},
 		&models.Subtask{},
 		&models.CardAssignee{},
 		&models.CardAttachment{},
+		&models.Label{},
 		&models.CardLabel{},
 		&models.CardComment{},
+		&models.CommentMention{},
+		&models.CommentReaction{},
+		&models.CommentRevision{},
+		&models.Notification{},
+		&models.DeadlineReminder{},
+		&models.NotificationPreference{},
+		&models.EmailDigestSetting{},
+		&models.CalendarToken{},
+		&models.TimeEntry{},
+		&models.UndoAction{},
+		&models.AutomationRule{},
+		&models.AutomationLog{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.ChatCommand{},
+		&models.ListInboundAddress{},
+		&models.CardLink{},
+		&models.CustomField{},
+		&models.CardCustomFieldValue{},
+		&models.CardTransition{},
 	); err != nil {
 		log.Fatalf("Failed to auto-migrate models: %v", err)
 	}
 
 	ensureCascadeFK(db)
+	migrateCardLabelsToCatalog(db)
+	ensureSearchIndexes(db)
+	backfillCardTransitions(db)
+	migrateInboundAttachmentURLs(db)
 
 	DB = db
 	log.Println("Database connected, migrated, and cascade constraints ensured")
@@ -98,10 +124,111 @@ func ensureCascadeFK(db *gorm.DB) {
 	// ensureCascadeConstraints(db, &models.YourNewModel{}, []string{"AssociationName1", "AssociationName2"})
 }
 
-// getEnv returns the environment variable or fallback if not set.
-func getEnv(key, fallback string) string {
-	if v, ok := os.LookupEnv(key); ok {
-		return v
+// migrateCardLabelsToCatalog moves labels stored per card (name and color columns on card_labels)
+// into the workspace label catalog. Labels with the same name (case-insensitive) in a workspace
+// become a single catalog entry, duplicate labels on the same card are removed, and the legacy
+// columns are dropped. It does nothing once the legacy columns are gone.
+func migrateCardLabelsToCatalog(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&models.CardLabel{}, "name") {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Create one catalog label per workspace and name, keeping the color of the oldest card label.
+		// Names are cut to the catalog's 100 characters and invalid colors replaced by the default.
+		if err := tx.Exec(`
+			INSERT INTO labels (workspace_id, name, color, created_at, updated_at)
+			SELECT DISTINCT ON (bl.workspace_id, LOWER(LEFT(TRIM(cl.name), 100)))
+				bl.workspace_id, LEFT(TRIM(cl.name), 100),
+				CASE WHEN cl.color ~ '^#[0-9A-Fa-f]{6}$' THEN cl.color ELSE ? END, NOW(), NOW()
+			FROM card_labels cl
+			JOIN cards c ON c.id = cl.card_id
+			JOIN board_lists bl ON bl.id = c.list_id
+			WHERE cl.label_id IS NULL
+			ORDER BY bl.workspace_id, LOWER(LEFT(TRIM(cl.name), 100)), cl.created_at, cl.id
+			ON CONFLICT DO NOTHING`, models.DefaultLabelColor).Error; err != nil {
+			return err
+		}
+
+		// Remove duplicate labels with the same name on the same card.
+		if err := tx.Exec(`
+			DELETE FROM card_labels a
+			USING card_labels b
+			WHERE a.card_id = b.card_id
+				AND LOWER(LEFT(TRIM(a.name), 100)) = LOWER(LEFT(TRIM(b.name), 100))
+				AND a.id > b.id
+				AND a.label_id IS NULL
+				AND b.label_id IS NULL`).Error; err != nil {
+			return err
+		}
+
+		// Point each card label at its catalog label.
+		if err := tx.Exec(`
+			UPDATE card_labels cl
+			SET label_id = l.id
+			FROM cards c, board_lists bl, labels l
+			WHERE c.id = cl.card_id
+				AND bl.id = c.list_id
+				AND l.workspace_id = bl.workspace_id
+				AND LOWER(l.name) = LOWER(LEFT(TRIM(cl.name), 100))
+				AND cl.label_id IS NULL`).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(&models.CardLabel{}, "name"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.CardLabel{}, "color")
+	})
+	if err != nil {
+		log.Fatalf("Failed to migrate card labels to the workspace label catalog: %v", err)
+	}
+	log.Println("Migrated card labels to the workspace label catalog")
+}
+
+// ensureSearchIndexes creates the GIN indexes used by full-text card search.
+// The indexed expressions must match the ones in repositories.SearchCards.
+func ensureSearchIndexes(db *gorm.DB) {
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_cards_search ON cards
+			USING GIN (to_tsvector('simple', COALESCE(title, '') || ' ' || COALESCE(description, '')))`,
+		`CREATE INDEX IF NOT EXISTS idx_card_comments_search ON card_comments
+			USING GIN (to_tsvector('simple', comment))`,
+	}
+	for _, index := range indexes {
+		if err := db.Exec(index).Error; err != nil {
+			log.Fatalf("Failed to create search index: %v", err)
+		}
+	}
+}
+
+// backfillCardTransitions gives cards created before list transitions were recorded a single
+// transition into their current list at their creation time, so analytics can include them.
+func backfillCardTransitions(db *gorm.DB) {
+	result := db.Exec(`
+		INSERT INTO card_transitions (card_id, to_list_id, moved_at)
+		SELECT c.id, c.list_id, c.created_at
+		FROM cards c
+		WHERE NOT EXISTS (SELECT 1 FROM card_transitions t WHERE t.card_id = c.id)`)
+	if result.Error != nil {
+		log.Fatalf("Failed to backfill card transitions: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Backfilled list transitions for %d cards", result.RowsAffected)
+	}
+}
+
+// migrateInboundAttachmentURLs replaces the file paths stored as the URL of email attachments
+// with their download URL.
+func migrateInboundAttachmentURLs(db *gorm.DB) {
+	result := db.Exec(`
+		UPDATE card_attachments
+		SET url = '/api/kanban/cards/' || card_id || '/inbound-attachments/' || substring(url from '[^/]+$')
+		WHERE url LIKE 'uploads/inbound/%'`)
+	if result.Error != nil {
+		log.Fatalf("Failed to migrate inbound attachment URLs: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Migrated the URLs of %d inbound attachments", result.RowsAffected)
 	}
-	return fallback
 }
@@ -3,25 +3,118 @@ package dto
 import (
 	"time"
 
+	"kelarin-backend/markdown"
 	"kelarin-backend/models"
 )
 
+// DeletedCommentPlaceholder is the text shown in place of a deleted comment.
+const DeletedCommentPlaceholder = "This comment has been deleted"
+
+// CommentMentionResponse represents a mention span in a comment.
+// Start and End are UTF-16 offsets of the "@handle" in the comment text.
+type CommentMentionResponse struct {
+	UserID   uint   `json:"user_id"`
+	FullName string `json:"fullname"`
+	Handle   string `json:"handle"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// CommentReactionResponse summarizes the reactions on a comment with a single emoji.
+type CommentReactionResponse struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	UserIDs []uint `json:"user_ids"`
+}
+
 // CardCommentResponse represents a comment on a card with user info mapped using ProfileResponse.
+// Replies are only filled in for top-level comments of a thread.
 type CardCommentResponse struct {
-	ID        uint            `json:"id"`
-	CardID    uint            `json:"card_id"`
-	User      ProfileResponse `json:"user"`
-	Comment   string          `json:"comment"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID        uint                      `json:"id"`
+	CardID    uint                      `json:"card_id"`
+	ParentID  *uint                     `json:"parent_id"`
+	User      ProfileResponse           `json:"user"`
+	Comment   string                    `json:"comment"`
+	HTML      string                    `json:"comment_html"`
+	Mentions  []CommentMentionResponse  `json:"mentions"`
+	Reactions []CommentReactionResponse `json:"reactions"`
+	Deleted   bool                      `json:"deleted"`
+	CreatedAt time.Time                 `json:"created_at"`
+	EditedAt  *time.Time                `json:"edited_at"`
+	Replies   []CardCommentResponse     `json:"replies,omitempty"`
 }
 
 // NewCardCommentResponse converts a CardComment model into a CardCommentResponse.
+// Deleted comments are returned as placeholders without their text, mentions or reactions.
 func NewCardCommentResponse(comment *models.CardComment) CardCommentResponse {
-	return CardCommentResponse{
+	response := CardCommentResponse{
 		ID:        comment.ID,
 		CardID:    comment.CardID,
+		ParentID:  comment.ParentID,
 		User:      NewProfileResponse(&comment.User),
 		Comment:   comment.Comment,
+		HTML:      markdown.ToHTML(comment.Comment),
+		Mentions:  make([]CommentMentionResponse, 0),
+		Reactions: make([]CommentReactionResponse, 0),
 		CreatedAt: comment.CreatedAt,
+		EditedAt:  comment.EditedAt,
+	}
+
+	if comment.DeletedAt != nil {
+		response.Comment = DeletedCommentPlaceholder
+		response.HTML = "<p><em>" + DeletedCommentPlaceholder + "</em></p>"
+		response.Deleted = true
+		return response
+	}
+
+	for _, m := range comment.Mentions {
+		response.Mentions = append(response.Mentions, CommentMentionResponse{
+			UserID:   m.UserID,
+			FullName: m.User.FullName,
+			Handle:   m.Handle,
+			Start:    m.Start,
+			End:      m.End,
+		})
+	}
+
+	index := make(map[string]int)
+	for _, r := range comment.Reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(response.Reactions)
+			index[r.Emoji] = i
+			response.Reactions = append(response.Reactions, CommentReactionResponse{Emoji: r.Emoji})
+		}
+		response.Reactions[i].Count++
+		response.Reactions[i].UserIDs = append(response.Reactions[i].UserIDs, r.UserID)
+	}
+
+	return response
+}
+
+// NewCardCommentThreads groups comments into threads: top-level comments in their original
+// order, each with its replies nested. Replies whose parent is missing are kept at the top level.
+func NewCardCommentThreads(comments []models.CardComment) []CardCommentResponse {
+	present := make(map[uint]bool, len(comments))
+	for _, comment := range comments {
+		present[comment.ID] = true
+	}
+
+	replies := make(map[uint][]CardCommentResponse)
+	for _, comment := range comments {
+		if comment.ParentID != nil && present[*comment.ParentID] {
+			replies[*comment.ParentID] = append(replies[*comment.ParentID], NewCardCommentResponse(&comment))
+		}
+	}
+
+	threads := make([]CardCommentResponse, 0)
+	for _, comment := range comments {
+		if comment.ParentID != nil && present[*comment.ParentID] {
+			continue
+		}
+		thread := NewCardCommentResponse(&comment)
+		thread.Replies = replies[comment.ID]
+		threads = append(threads, thread)
 	}
+	return threads
 }
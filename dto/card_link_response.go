@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"time"
+
+	"kelarin-backend/models"
+)
+
+// CardLinkResponse represents a link as seen from one of its cards: Type is read as
+// "this card <type> Card", using the inverse type for links pointing to this card.
+type CardLinkResponse struct {
+	ID        uint               `json:"id"`
+	Type      string             `json:"type"`
+	Card      LinkedCardResponse `json:"card"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// LinkedCardResponse is the other card of a link.
+type LinkedCardResponse struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	ListID      uint       `json:"list_id"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// NewCardLinkResponses converts the links of a card, with both cards preloaded, into
+// CardLinkResponses seen from that card. Links to cards in the trash are left out.
+func NewCardLinkResponses(cardID uint, links []models.CardLink) []CardLinkResponse {
+	responses := make([]CardLinkResponse, 0, len(links))
+	for _, link := range links {
+		linkType, other := link.Type, link.LinkedCard
+		if link.LinkedCardID == cardID {
+			other = link.Card
+			switch link.Type {
+			case models.CardLinkBlocks:
+				linkType = models.CardLinkBlockedBy
+			case models.CardLinkDuplicates:
+				linkType = models.CardLinkDuplicatedBy
+			}
+		}
+		if other.ID == 0 {
+			continue
+		}
+
+		responses = append(responses, CardLinkResponse{
+			ID:   link.ID,
+			Type: linkType,
+			Card: LinkedCardResponse{
+				ID:          other.ID,
+				Title:       other.Title,
+				ListID:      other.ListID,
+				CompletedAt: other.CompletedAt,
+			},
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	return responses
+}
+
+// IsBlocked reports whether any of the links is a blocked_by link to a card that is not completed.
+func IsBlocked(links []CardLinkResponse) bool {
+	for _, link := range links {
+		if link.Type == models.CardLinkBlockedBy && link.Card.CompletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"time"
+
+	"kelarin-backend/markdown"
+	"kelarin-backend/models"
+)
+
+// CardContextResponse identifies the workspace or list a card belongs to.
+type CardContextResponse struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// CardSummaryResponse represents a card outside of its board, with its workspace and list for context.
+type CardSummaryResponse struct {
+	ID              uint                `json:"id"`
+	Title           string              `json:"title"`
+	Description     string              `json:"description"`
+	DescriptionHTML string              `json:"description_html"`
+	Deadline        *time.Time          `json:"deadline,omitempty"`
+	DeadlineBucket  string              `json:"deadline_bucket"`
+	Workspace       CardContextResponse `json:"workspace"`
+	List            CardContextResponse `json:"list"`
+	Labels          []models.Label      `json:"labels"`
+	SubtasksDone    int                 `json:"subtasks_done"`
+	SubtasksTotal   int                 `json:"subtasks_total"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// NewCardSummaryResponse converts a Card model, with its list and workspace preloaded, into a CardSummaryResponse.
+func NewCardSummaryResponse(card *models.Card, bucket string) CardSummaryResponse {
+	labels := make([]models.Label, len(card.Labels))
+	for i, l := range card.Labels {
+		labels[i] = l.Label
+	}
+
+	done := 0
+	for _, s := range card.Subtasks {
+		if s.IsDone {
+			done++
+		}
+	}
+
+	return CardSummaryResponse{
+		ID:              card.ID,
+		Title:           card.Title,
+		Description:     card.Description,
+		DescriptionHTML: markdown.ToHTML(card.Description),
+		Deadline:        card.Deadline,
+		DeadlineBucket:  bucket,
+		Workspace: CardContextResponse{
+			ID:    card.List.Workspace.ID,
+			Title: card.List.Workspace.Title,
+		},
+		List: CardContextResponse{
+			ID:    card.List.ID,
+			Title: card.List.Title,
+		},
+		Labels:        labels,
+		SubtasksDone:  done,
+		SubtasksTotal: len(card.Subtasks),
+		CreatedAt:     card.CreatedAt,
+		UpdatedAt:     card.UpdatedAt,
+	}
+}
@@ -0,0 +1,9 @@
+package dto
+
+// MentionCandidateResponse represents a workspace member suggested while typing an @mention.
+type MentionCandidateResponse struct {
+	ID       uint   `json:"id"`
+	FullName string `json:"fullname"`
+	Email    string `json:"email"`
+	Handle   string `json:"handle"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"kelarin-backend/models"
+)
+
+// TimeEntryResponse represents a time entry with its tracked duration so far.
+type TimeEntryResponse struct {
+	ID              uint            `json:"id"`
+	CardID          uint            `json:"card_id"`
+	StartedAt       time.Time       `json:"started_at"`
+	EndedAt         *time.Time      `json:"ended_at"`
+	DurationSeconds int64           `json:"duration_seconds"`
+	Running         bool            `json:"running"`
+	Manual          bool            `json:"manual"`
+	Note            string          `json:"note"`
+	User            ProfileResponse `json:"user"`
+}
+
+// TimeTotalResponse is the time tracked on a card or by a user, in seconds.
+type TimeTotalResponse struct {
+	CardID  uint  `json:"card_id,omitempty"`
+	UserID  uint  `json:"user_id,omitempty"`
+	Seconds int64 `json:"seconds"`
+}
+
+// NewTimeEntryResponse converts a TimeEntry model into a TimeEntryResponse. The duration of a
+// running timer is counted up to now.
+func NewTimeEntryResponse(entry *models.TimeEntry, now time.Time) TimeEntryResponse {
+	duration := entry.DurationSeconds
+	if entry.EndedAt == nil {
+		duration = int64(now.Sub(entry.StartedAt).Seconds())
+	}
+
+	return TimeEntryResponse{
+		ID:              entry.ID,
+		CardID:          entry.CardID,
+		StartedAt:       entry.StartedAt,
+		EndedAt:         entry.EndedAt,
+		DurationSeconds: duration,
+		Running:         entry.EndedAt == nil,
+		Manual:          entry.Manual,
+		Note:            entry.Note,
+		User:            NewProfileResponse(&entry.User),
+	}
+}
@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// TrashedWorkspaceResponse represents a workspace in the trash.
+type TrashedWorkspaceResponse struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedListResponse represents a board list in the trash.
+type TrashedListResponse struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	CardCount int64     `json:"card_count"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedCardResponse represents a card in the trash with the list it belonged to.
+type TrashedCardResponse struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	ListID    uint      `json:"list_id"`
+	ListTitle string    `json:"list_title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// NewTrashedWorkspaceResponse converts a deleted Workspace into a TrashedWorkspaceResponse.
+func NewTrashedWorkspaceResponse(workspace *models.Workspace, retention time.Duration) TrashedWorkspaceResponse {
+	return TrashedWorkspaceResponse{
+		ID:        workspace.ID,
+		Title:     workspace.Title,
+		DeletedAt: workspace.DeletedAt.Time,
+		PurgeAt:   workspace.DeletedAt.Time.Add(retention),
+	}
+}
+
+// NewTrashedListResponse converts a TrashedList into a TrashedListResponse.
+func NewTrashedListResponse(list *repositories.TrashedList, retention time.Duration) TrashedListResponse {
+	return TrashedListResponse{
+		ID:        list.ID,
+		Title:     list.Title,
+		CardCount: list.CardCount,
+		DeletedAt: list.DeletedAt.Time,
+		PurgeAt:   list.DeletedAt.Time.Add(retention),
+	}
+}
+
+// NewTrashedCardResponse converts a TrashedCard into a TrashedCardResponse.
+func NewTrashedCardResponse(card *repositories.TrashedCard, retention time.Duration) TrashedCardResponse {
+	return TrashedCardResponse{
+		ID:        card.ID,
+		Title:     card.Title,
+		ListID:    card.ListID,
+		ListTitle: card.ListTitle,
+		DeletedAt: card.DeletedAt.Time,
+		PurgeAt:   card.DeletedAt.Time.Add(retention),
+	}
+}
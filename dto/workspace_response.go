@@ -27,6 +27,7 @@ type WorkspaceResponse struct {
 	WorkspaceBanner  string                  `json:"workspace_banner"`
 	Owner            UserResponse            `json:"owner"`
 	Collaborators    []WorkspaceUserResponse `json:"collaborators"`
+	Version          uint                    `json:"version"`
 }
 
 // NewWorkspaceResponse converts a Workspace model to a WorkspaceResponse DTO.
@@ -54,5 +55,6 @@ func NewWorkspaceResponse(w *models.Workspace) WorkspaceResponse {
 			Email:    w.Owner.Email,
 		},
 		Collaborators: collaborators,
+		Version:       w.Version,
 	}
 }
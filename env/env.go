@@ -0,0 +1,48 @@
+// Package env reads configuration from environment variables.
+package env
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Get returns the environment variable or fallback if not set.
+func Get(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+// Duration parses the environment variable as a duration (e.g., "5m"),
+// returning fallback if it is not set or invalid.
+func Duration(key string, fallback time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration %q for %s, using %s", v, key, fallback)
+		return fallback
+	}
+	return d
+}
+
+// Bool parses the environment variable as a boolean (e.g., "true" or "1"),
+// returning fallback if it is not set or invalid.
+func Bool(key string, fallback bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		log.Printf("Invalid boolean %q for %s, using %t", v, key, fallback)
+		return fallback
+	}
+	return b
+}
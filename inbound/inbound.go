@@ -0,0 +1,188 @@
+// Package inbound parses inbound emails, either from raw MIME messages or from the fields
+// that inbound-mail providers post after parsing a message themselves.
+package inbound
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxPartDepth limits how deeply nested multipart messages are parsed.
+const maxPartDepth = 10
+
+// Email is an inbound email message.
+type Email struct {
+	From        string
+	To          []string // To and Cc addresses, and any envelope recipients the provider reports
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an inbound email.
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// wordDecoder decodes RFC 2047 encoded words in headers and file names, in any charset.
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// ParseMIME parses a raw RFC 5322 message. Text and HTML bodies are taken from the first
+// text/plain and text/html parts that are not attachments; every other part with a file name
+// or an attachment disposition becomes an Attachment.
+func ParseMIME(r io.Reader) (*Email, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	email := &Email{
+		From:    DecodeHeader(msg.Header.Get("From")),
+		Subject: DecodeHeader(msg.Header.Get("Subject")),
+	}
+	for _, field := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		email.To = append(email.To, ParseAddresses(msg.Header.Get(field))...)
+	}
+
+	if err := parsePart(email, msg.Header, msg.Body, 0); err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+// DecodeHeader decodes RFC 2047 encoded words in a header value, returning the value as is if
+// it cannot be decoded.
+func DecodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// ParseAddresses returns the lowercased email addresses of an address list header such as
+// "Team <abc@inbound.example.com>, other@example.com". Entries that cannot be parsed as an
+// address list are split on commas instead.
+func ParseAddresses(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	var addresses []string
+	if list, err := (&mail.AddressParser{WordDecoder: wordDecoder}).ParseList(value); err == nil {
+		for _, addr := range list {
+			addresses = append(addresses, strings.ToLower(addr.Address))
+		}
+		return addresses
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if start := strings.LastIndex(part, "<"); start >= 0 {
+			part = strings.TrimSuffix(part[start+1:], ">")
+		}
+		if strings.Contains(part, "@") {
+			addresses = append(addresses, strings.ToLower(strings.TrimSpace(part)))
+		}
+	}
+	return addresses
+}
+
+// header is the subset of mail.Header and textproto.MIMEHeader that parsePart needs.
+type header interface {
+	Get(key string) string
+}
+
+// parsePart adds one MIME part, and for multipart parts every part inside it, to the email.
+func parsePart(email *Email, h header, body io.Reader, depth int) error {
+	if depth > maxPartDepth {
+		return errors.New("message is nested too deeply")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := parsePart(email, part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	fileName = DecodeHeader(fileName)
+
+	switch {
+	case disposition != "attachment" && fileName == "" && mediaType == "text/plain" && email.Text == "":
+		email.Text = decodeCharset(params["charset"], data)
+	case disposition != "attachment" && fileName == "" && mediaType == "text/html" && email.HTML == "":
+		email.HTML = decodeCharset(params["charset"], data)
+	case disposition == "attachment" || fileName != "":
+		if fileName == "" {
+			fileName = "attachment"
+		}
+		email.Attachments = append(email.Attachments, Attachment{
+			FileName:    fileName,
+			ContentType: mediaType,
+			Data:        data,
+		})
+	}
+	return nil
+}
+
+// decodeCharset converts a text body from the charset of its part to UTF-8. A body in an
+// unknown charset is kept as is, and any bytes that are still not valid UTF-8 are replaced.
+func decodeCharset(label string, data []byte) string {
+	if label != "" {
+		if r, err := charset.NewReaderLabel(label, bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil {
+				data = decoded
+			}
+		}
+	}
+	return strings.ToValidUTF8(string(data), "\uFFFD")
+}
+
+// decodeTransferEncoding wraps a part body in a decoder for its Content-Transfer-Encoding.
+// The base64 decoder skips the line breaks base64 bodies are wrapped with.
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
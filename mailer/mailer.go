@@ -0,0 +1,133 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"kelarin-backend/env"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatDate": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format("Mon, 02 Jan 2006 15:04 MST")
+	},
+}).ParseFS(templateFS, "templates/*.html"))
+
+// Message is an email to be delivered to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer delivers messages through an SMTP server.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers the message through the configured SMTP server.
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, buildMIME(m.From, msg))
+}
+
+// FileMailer writes each message as an .eml file into Dir instead of sending it.
+// It is meant for local development and testing.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Send writes the message to a new file in the mailer directory.
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMIME(m.From, msg), 0o644)
+}
+
+// NewFromEnv returns the mailer configured by the MAILER environment variable:
+// "smtp" uses SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD, while
+// "file" (the default) writes messages into MAIL_DIR (default "./mail_outbox").
+func NewFromEnv() Mailer {
+	from := env.Get("MAIL_FROM", "KelarIn <no-reply@kelarin.bccdev.id>")
+
+	if env.Get("MAILER", "file") == "smtp" {
+		return &SMTPMailer{
+			Host:     env.Get("SMTP_HOST", "localhost"),
+			Port:     env.Get("SMTP_PORT", "587"),
+			Username: env.Get("SMTP_USERNAME", ""),
+			Password: env.Get("SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	}
+
+	dir := env.Get("MAIL_DIR", filepath.Join(".", "mail_outbox"))
+	log.Printf("Using file mailer, emails are written to %s", dir)
+	return &FileMailer{Dir: dir, From: from}
+}
+
+// Render executes the named HTML email template with the given data.
+func Render(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// buildMIME formats a message as an RFC 5322 email with an HTML body.
+func buildMIME(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + encodeAddress(from) + "\r\n")
+	b.WriteString("To: " + encodeAddress(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.HTML)
+	return []byte(b.String())
+}
+
+// encodeAddress formats an address header value, RFC 2047-encoding a display name that is not
+// plain ASCII. Values that do not parse as an address are encoded as a whole.
+func encodeAddress(raw string) string {
+	addr, err := mail.ParseAddress(raw)
+	if err != nil {
+		return mime.QEncoding.Encode("UTF-8", raw)
+	}
+	return addr.String()
+}
@@ -4,7 +4,9 @@ import (
 	"log"
 
 	"kelarin-backend/database"
+	"kelarin-backend/mailer"
 	"kelarin-backend/routes"
+	"kelarin-backend/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,6 +16,21 @@ func main() {
 	// Connect, migrate, and ensure cascade‑delete constraint
 	database.ConnectDatabase()
 
+	// Send deadline reminders and overdue notifications in the background
+	utils.StartDeadlineReminderScheduler()
+
+	// Send opt-in daily/weekly email digests in the background
+	utils.StartEmailDigestScheduler(mailer.NewFromEnv())
+
+	// Permanently delete trashed workspaces, lists and cards after the retention period
+	utils.StartTrashPurgeScheduler()
+
+	// Fire deadline_passed automation rules for overdue cards
+	utils.StartAutomationScheduler()
+
+	// Deliver queued webhook events and retry failed deliveries
+	utils.StartWebhookDeliveryWorker()
+
 	// Initialize Fiber
 	app := fiber.New(fiber.Config{
 		BodyLimit: 20 * 1024 * 1024, // 20 MB
@@ -22,8 +39,9 @@ func main() {
 	// CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:3000, https://kelar-in.vercel.app, https://kelarin.bccdev.id",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Content-Type, Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Content-Type, Authorization, If-Match",
+		ExposeHeaders:    "ETag",
 		AllowCredentials: true,
 	}))
 
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Automation log statuses.
+const (
+	AutomationStatusSuccess = "success"
+	AutomationStatusFailed  = "failed"
+	AutomationStatusSkipped = "skipped"
+)
+
+// AutomationLog records one execution of an automation rule on a card.
+type AutomationLog struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RuleID      uint      `gorm:"not null;index" json:"rule_id"`
+	WorkspaceID uint      `gorm:"not null;index" json:"workspace_id"`
+	CardID      uint      `gorm:"not null;index" json:"card_id"`
+	Trigger     string    `gorm:"not null;size:30" json:"trigger"`
+	Status      string    `gorm:"not null;size:20" json:"status"` // "success", "failed" or "skipped"
+	Message     string    `gorm:"type:text" json:"message"`
+	Depth       int       `gorm:"not null" json:"depth"` // 0 for events caused by a user, N for events caused by another rule
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+
+	Rule AutomationRule `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Automation triggers.
+const (
+	AutomationTriggerCardCreated       = "card_created"
+	AutomationTriggerCardMoved         = "card_moved"
+	AutomationTriggerCardCompleted     = "card_completed"
+	AutomationTriggerLabelAdded        = "label_added"
+	AutomationTriggerSubtasksCompleted = "subtasks_completed"
+	AutomationTriggerListCompleted     = "list_completed"
+	AutomationTriggerDeadlinePassed    = "deadline_passed"
+	AutomationTriggerBlockedCardMoved  = "blocked_card_moved" // A card with open blockers was moved to a completed list
+)
+
+// Automation actions.
+const (
+	AutomationActionCompleteSubtasks = "complete_subtasks"
+	AutomationActionCompleteCard     = "complete_card"
+	AutomationActionArchiveCard      = "archive_card"
+	AutomationActionMoveToList       = "move_to_list"
+	AutomationActionAddLabel         = "add_label"
+	AutomationActionAssignUser       = "assign_user"
+	AutomationActionAssignOwner      = "assign_owner"
+)
+
+// AutomationRule is a "when X then Y" rule of a workspace. When its trigger fires for a card
+// that matches the optional list or label condition, its actions are run on that card in order.
+type AutomationRule struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	WorkspaceID    uint              `gorm:"not null;index" json:"workspace_id"`
+	Name           string            `gorm:"not null;size:100" json:"name"`
+	Trigger        string            `gorm:"not null;size:30" json:"trigger"`
+	TriggerListID  *uint             `json:"trigger_list_id"`  // Only cards in this list (for card_moved, the list moved to)
+	TriggerLabelID *uint             `json:"trigger_label_id"` // label_added: only when this label is added
+	Actions        AutomationActions `gorm:"type:text;not null" json:"actions"`
+	Enabled        bool              `gorm:"not null;default:true" json:"enabled"`
+	CreatedByID    uint              `gorm:"not null" json:"created_by_id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedBy User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// AutomationAction is one step of an automation rule. Only the field used by the action
+// type is read: ListID for move_to_list, LabelID for add_label and UserID for assign_user.
+type AutomationAction struct {
+	Type    string `json:"type"`
+	ListID  uint   `json:"list_id,omitempty"`
+	LabelID uint   `json:"label_id,omitempty"`
+	UserID  uint   `json:"user_id,omitempty"`
+}
+
+// AutomationActions is the ordered list of actions of a rule, stored as JSON.
+type AutomationActions []AutomationAction
+
+// Value stores the actions as a JSON array.
+func (a AutomationActions) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+// Scan reads the actions from a JSON array.
+func (a *AutomationActions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case []byte:
+		return json.Unmarshal(v, a)
+	case nil:
+		*a = nil
+		return nil
+	}
+	return errors.New("unsupported type for automation actions")
+}
@@ -1,14 +1,30 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WIP limit modes.
+const (
+	WIPLimitHard = "hard" // Cards cannot be added to a full list
+	WIPLimitSoft = "soft" // Cards can be added to a full list, with a warning
+)
 
 // BoardList represents a list/column on a Kanban board (e.g., To Do, In Progress).
 type BoardList struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Title       string    `gorm:"not null" json:"title"`
-	WorkspaceID uint      `gorm:"not null" json:"workspace_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Title        string         `gorm:"not null" json:"title"`
+	WorkspaceID  uint           `gorm:"not null" json:"workspace_id"`
+	CompletedAt  *time.Time     `json:"completed_at"`
+	WIPLimit     int            `gorm:"column:wip_limit;not null;default:0" json:"wip_limit"`                        // Most cards the list should hold, 0 for no limit
+	WIPLimitMode string         `gorm:"column:wip_limit_mode;size:10;not null;default:'soft'" json:"wip_limit_mode"` // WIPLimitHard or WIPLimitSoft
+	ArchivedAt   *time.Time     `json:"archived_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Version      uint           `gorm:"not null;default:1" json:"version"` // Incremented on every update, used as the ETag
 
 	// The workspace this list belongs to.
 	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
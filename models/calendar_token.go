@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CalendarToken is the secret token of an iCalendar subscription URL.
+// A token without a WorkspaceID is the user's personal feed of the cards assigned to them;
+// otherwise it is the user's feed of every card in that workspace.
+type CalendarToken struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Token       string    `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	WorkspaceID *uint     `gorm:"index" json:"workspace_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Workspace *Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+}
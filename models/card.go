@@ -1,22 +1,84 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"kelarin-backend/markdown"
+
+	"gorm.io/gorm"
+)
+
+// Card recurrence rules.
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// Card priorities, from lowest to highest.
+const (
+	PriorityNone   = "none"
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
 
 // Card represents a card in a Kanban list.
 type Card struct {
-	ID          uint       `gorm:"primaryKey" json:"id"`
-	Title       string     `gorm:"not null" json:"title"`
-	Description string     `json:"description"`
-	Deadline    *time.Time `json:"deadline,omitempty"`
-	ListID      uint       `gorm:"not null" json:"list_id"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Title              string         `gorm:"not null" json:"title"`
+	Description        string         `json:"description"`
+	CompletionPercent  *int           `gorm:"-" json:"completion_percent,omitempty"` // Share of done subtasks, set when listing cards
+	StartDate          *time.Time     `json:"start_date,omitempty"`
+	Deadline           *time.Time     `json:"deadline,omitempty"`
+	ListID             uint           `gorm:"not null" json:"list_id"`
+	Priority           string         `gorm:"size:10;not null;default:'none'" json:"priority"` // One of the Priority constants
+	CompletedAt        *time.Time     `json:"completed_at"`
+	ArchivedAt         *time.Time     `json:"archived_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	Recurrence         string         `gorm:"size:20" json:"recurrence"`                     // "daily", "weekly", "monthly" or empty for a one-off card
+	RecurrenceInterval int            `gorm:"not null;default:1" json:"recurrence_interval"` // Repeat every N days, weeks or months
+	NextOccurrenceID   *uint          `json:"next_occurrence_id,omitempty"`                  // Card created when this recurring card was completed
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	Version            uint           `gorm:"not null;default:1" json:"version"` // Incremented on every update, used as the ETag
 
 	// The list this card belongs to.
-	List        BoardList        `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE" json:"-"`
-	Subtasks    []Subtask        `gorm:"foreignKey:CardID" json:"subtasks"`
-	Assignees   []CardAssignee   `gorm:"foreignKey:CardID" json:"assignees"`
-	Attachments []CardAttachment `gorm:"foreignKey:CardID" json:"attachments"`
-	Labels      []CardLabel      `gorm:"foreignKey:CardID" json:"labels"`
-	Comments    []CardComment    `gorm:"foreignKey:CardID" json:"comments"`
+	List         BoardList              `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE" json:"-"`
+	Subtasks     []Subtask              `gorm:"foreignKey:CardID" json:"subtasks"`
+	Checklists   []Checklist            `gorm:"foreignKey:CardID" json:"checklists"`
+	CustomFields []CardCustomFieldValue `gorm:"foreignKey:CardID" json:"custom_fields"`
+	Assignees    []CardAssignee         `gorm:"foreignKey:CardID" json:"assignees"`
+	Attachments  []CardAttachment       `gorm:"foreignKey:CardID" json:"attachments"`
+	Labels       []CardLabel            `gorm:"foreignKey:CardID" json:"labels"`
+	Comments     []CardComment          `gorm:"foreignKey:CardID" json:"comments"`
+}
+
+// BeforeCreate gives a new card no priority unless one is set.
+func (c *Card) BeforeCreate(tx *gorm.DB) error {
+	if c.Priority == "" {
+		c.Priority = PriorityNone
+	}
+	return nil
+}
+
+// AfterCreate records the card entering its first list.
+func (c *Card) AfterCreate(tx *gorm.DB) error {
+	movedAt := c.CreatedAt
+	if movedAt.IsZero() {
+		movedAt = time.Now()
+	}
+	return tx.Create(&CardTransition{CardID: c.ID, ToListID: c.ListID, MovedAt: movedAt}).Error
+}
+
+// MarshalJSON writes the card with description_html, the sanitised HTML rendering of its
+// Markdown description. It is rendered here, only for cards sent in a response.
+func (c Card) MarshalJSON() ([]byte, error) {
+	type card Card
+	return json.Marshal(struct {
+		card
+		DescriptionHTML string `json:"description_html"`
+	}{card(c), markdown.ToHTML(c.Description)})
 }
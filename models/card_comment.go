@@ -1,15 +1,40 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"kelarin-backend/markdown"
+)
 
 // CardComment represents a comment on a card.
+// Replies reference the top-level comment of their thread through ParentID.
+// Deleted comments keep their row, with the text cleared, so threads stay intact.
 type CardComment struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	CardID    uint      `gorm:"not null" json:"card_id"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	Comment   string    `gorm:"not null" json:"comment"`
-	CreatedAt time.Time `json:"created_at"`
-
-	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
-	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CardID    uint       `gorm:"not null" json:"card_id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	ParentID  *uint      `gorm:"index" json:"parent_id"`
+	Comment   string     `gorm:"not null" json:"comment"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+
+	Card   Card         `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	User   User         `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+	Parent *CardComment `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"-"`
+	// Workspace members mentioned in the comment.
+	Mentions []CommentMention `gorm:"foreignKey:CommentID" json:"mentions"`
+	// Emoji reactions on the comment.
+	Reactions []CommentReaction `gorm:"foreignKey:CommentID" json:"reactions"`
+}
+
+// MarshalJSON writes the comment with comment_html, the sanitised HTML rendering of its
+// Markdown text. It is rendered here, only for comments sent in a response.
+func (c CardComment) MarshalJSON() ([]byte, error) {
+	type comment CardComment
+	return json.Marshal(struct {
+		comment
+		CommentHTML string `json:"comment_html"`
+	}{comment(c), markdown.ToHTML(c.Comment)})
 }
@@ -0,0 +1,24 @@
+package models
+
+// CardCustomFieldValue is the value of a custom field on a card.
+type CardCustomFieldValue struct {
+	ID      uint      `gorm:"primaryKey" json:"-"`
+	CardID  uint      `gorm:"not null;uniqueIndex:idx_card_custom_field" json:"card_id"`
+	FieldID uint      `gorm:"not null;index;uniqueIndex:idx_card_custom_field" json:"field_id"`
+	Value   JSONValue `gorm:"type:text;not null" json:"value"`
+
+	Card  Card         `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Field *CustomField `gorm:"foreignKey:FieldID;constraint:OnDelete:CASCADE" json:"field,omitempty"`
+}
+
+// JSONValue is a JSON document stored as text and written as is in JSON responses, so a custom
+// field value keeps its type: a string, number, boolean or array of strings.
+type JSONValue string
+
+// MarshalJSON returns the stored JSON, or null if it is empty.
+func (v JSONValue) MarshalJSON() ([]byte, error) {
+	if v == "" {
+		return []byte("null"), nil
+	}
+	return []byte(v), nil
+}
@@ -2,13 +2,13 @@ package models
 
 import "time"
 
-// CardLabel represents a label on a card.
+// CardLabel links a Card with a Label from its workspace's label catalog.
 type CardLabel struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	CardID    uint      `gorm:"not null" json:"card_id"`
-	Name      string    `gorm:"not null" json:"name"`
-	Color     string    `json:"color"` // e.g., hex code or color name
+	CardID    uint      `gorm:"not null;uniqueIndex:idx_card_label" json:"card_id"`
+	LabelID   uint      `gorm:"uniqueIndex:idx_card_label" json:"label_id"`
 	CreatedAt time.Time `json:"created_at"`
 
-	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Card  Card  `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Label Label `gorm:"foreignKey:LabelID;constraint:OnDelete:CASCADE" json:"label"`
 }
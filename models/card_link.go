@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Card link types. A link reads "card <type> linked card", e.g. card 1 blocks card 2.
+const (
+	CardLinkBlocks     = "blocks"
+	CardLinkRelatesTo  = "relates_to"
+	CardLinkDuplicates = "duplicates"
+)
+
+// Inverse card link types, used when a link is seen from its linked card.
+const (
+	CardLinkBlockedBy    = "blocked_by"
+	CardLinkDuplicatedBy = "duplicated_by"
+)
+
+// CardLink is a typed relationship between two cards of the same workspace.
+type CardLink struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CardID       uint      `gorm:"not null;uniqueIndex:idx_card_link" json:"card_id"`
+	LinkedCardID uint      `gorm:"not null;index;uniqueIndex:idx_card_link" json:"linked_card_id"`
+	Type         string    `gorm:"not null;size:20;uniqueIndex:idx_card_link" json:"type"`
+	CreatedByID  uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Card       Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	LinkedCard Card `gorm:"foreignKey:LinkedCardID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedBy  User `gorm:"foreignKey:CreatedByID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CardTransition records a card entering a list, either when it is created (FromListID is nil)
+// or when it is moved from another list. The history of transitions feeds workspace analytics.
+type CardTransition struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CardID     uint      `gorm:"not null;index" json:"card_id"`
+	FromListID *uint     `json:"from_list_id"`
+	ToListID   uint      `gorm:"not null" json:"to_list_id"`
+	MovedAt    time.Time `gorm:"not null;index" json:"moved_at"`
+
+	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Chat providers.
+const (
+	ChatProviderSlack   = "slack"
+	ChatProviderDiscord = "discord"
+)
+
+// ChatCommand lets a chat workspace create cards in a board list through a slash command.
+// Requests are verified with SigningKey: the Slack app's signing secret, or the Discord
+// application's hex-encoded Ed25519 public key.
+type ChatCommand struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint      `gorm:"not null;index" json:"workspace_id"`
+	Provider    string    `gorm:"not null;size:20" json:"provider"` // "slack" or "discord"
+	ListID      uint      `gorm:"not null" json:"list_id"`          // Cards created from chat are added to this list
+	SigningKey  string    `gorm:"not null;size:255" json:"-"`
+	Enabled     bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedByID uint      `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+	List      BoardList `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedBy User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Checklist is a named group of checklist items (subtasks) on a card.
+type Checklist struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CardID    uint      `gorm:"not null;index" json:"card_id"`
+	Title     string    `gorm:"not null;size:255" json:"title"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// The card this checklist belongs to.
+	Card  Card      `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Items []Subtask `gorm:"foreignKey:ChecklistID" json:"items"`
+}
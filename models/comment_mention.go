@@ -0,0 +1,16 @@
+package models
+
+// CommentMention records a workspace member mentioned in a card comment.
+// Start and End are UTF-16 offsets of the "@handle" span in the comment text,
+// matching how JavaScript clients index strings.
+type CommentMention struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	CommentID uint   `gorm:"not null;index" json:"comment_id"`
+	UserID    uint   `gorm:"not null" json:"user_id"`
+	Handle    string `gorm:"not null" json:"handle"`
+	Start     int    `gorm:"not null" json:"start"`
+	End       int    `gorm:"not null" json:"end"`
+
+	Comment CardComment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
+	User    User        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CommentReaction is an emoji reaction of a user on a card comment.
+type CommentReaction struct {
+	CommentID uint      `gorm:"primaryKey" json:"comment_id"`
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Emoji     string    `gorm:"primaryKey;size:32" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Comment CardComment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
+	User    User        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CommentRevision stores the text a card comment had before it was edited.
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"not null;index" json:"comment_id"`
+	Comment   string    `gorm:"not null" json:"comment"`
+	EditedBy  uint      `gorm:"not null" json:"edited_by"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CardComment CardComment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Custom field types.
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date"
+	CustomFieldSelect      = "select"
+	CustomFieldMultiSelect = "multi_select"
+	CustomFieldCheckbox    = "checkbox"
+	CustomFieldUser        = "user"
+)
+
+// CustomField is a field defined by a workspace that every card of the workspace can have a
+// value for, such as story points or a customer name.
+type CustomField struct {
+	ID          uint               `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint               `gorm:"not null;index" json:"workspace_id"`
+	Name        string             `gorm:"not null;size:100" json:"name"`
+	Type        string             `gorm:"not null;size:20" json:"type"`
+	Options     CustomFieldOptions `gorm:"type:text;not null" json:"options"` // The choices of select and multi_select fields
+	Position    int                `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// CustomFieldOptions is the list of choices of a select field, stored as JSON.
+type CustomFieldOptions []string
+
+// Value stores the options as a JSON array.
+func (o CustomFieldOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(o)
+	return string(data), err
+}
+
+// Scan reads the options from a JSON array.
+func (o *CustomFieldOptions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case []byte:
+		return json.Unmarshal(v, o)
+	case nil:
+		*o = nil
+		return nil
+	}
+	return errors.New("unsupported type for custom field options")
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DeadlineReminder records that a deadline reminder has been sent to a user for a card.
+// Kind is either a lead time (e.g., "24h0m0s") or "overdue". Deadline is the card deadline
+// the reminder was sent for, so changing a card's deadline allows new reminders to be sent.
+type DeadlineReminder struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CardID    uint      `gorm:"not null;uniqueIndex:idx_deadline_reminder" json:"card_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_deadline_reminder" json:"user_id"`
+	Kind      string    `gorm:"not null;size:50;uniqueIndex:idx_deadline_reminder" json:"kind"`
+	Deadline  time.Time `gorm:"not null;uniqueIndex:idx_deadline_reminder" json:"deadline"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Email digest frequencies.
+const (
+	DigestFrequencyNone   = "none"
+	DigestFrequencyDaily  = "daily"
+	DigestFrequencyWeekly = "weekly"
+)
+
+// EmailDigestSetting stores a user's opt-in for periodic email digests.
+type EmailDigestSetting struct {
+	UserID     uint       `gorm:"primaryKey" json:"user_id"`
+	Frequency  string     `gorm:"not null;size:20;default:'none'" json:"frequency"` // "none", "daily" or "weekly"
+	LastSentAt *time.Time `json:"last_sent_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
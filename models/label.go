@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// DefaultLabelColor is used for labels created without a color.
+const DefaultLabelColor = "#9CA3AF"
+
+// Label is a workspace-scoped label definition that cards reference through CardLabel.
+type Label struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint      `gorm:"not null;uniqueIndex:idx_label_workspace_name" json:"workspace_id"`
+	Name        string    `gorm:"not null;size:100;uniqueIndex:idx_label_workspace_name" json:"name"`
+	Color       string    `gorm:"not null;size:7" json:"color"` // Hex code, e.g., "#FF0000"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// The workspace this label belongs to.
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+}
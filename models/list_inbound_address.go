@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ListInboundAddress is the secret inbound email address of a board list. Emails sent to
+// "<token>@<inbound domain>" become cards in the list.
+type ListInboundAddress struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ListID    uint      `gorm:"uniqueIndex;not null" json:"list_id"`
+	Token     string    `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+
+	List BoardList `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE" json:"-"`
+}
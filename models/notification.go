@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Notification types.
+const (
+	NotificationTypeDeadlineReminder = "deadline_reminder"
+	NotificationTypeCardOverdue      = "card_overdue"
+	NotificationTypeCardAssigned     = "card_assigned"
+	NotificationTypeCommentMention   = "comment_mention"
+	NotificationTypeWorkspaceShared  = "workspace_shared"
+)
+
+// NotificationTypes lists every notification type a user can set preferences for.
+var NotificationTypes = []string{
+	NotificationTypeDeadlineReminder,
+	NotificationTypeCardOverdue,
+	NotificationTypeCardAssigned,
+	NotificationTypeCommentMention,
+	NotificationTypeWorkspaceShared,
+}
+
+// Notification represents an in-app notification delivered to a user.
+type Notification struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Type        string     `gorm:"not null;size:50" json:"type"`
+	Title       string     `gorm:"not null" json:"title"`
+	Message     string     `json:"message"`
+	WorkspaceID *uint      `json:"workspace_id,omitempty"`
+	CardID      *uint      `json:"card_id,omitempty"`
+	ActorID     *uint      `json:"actor_id,omitempty"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// The user receiving the notification.
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
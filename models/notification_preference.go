@@ -0,0 +1,11 @@
+package models
+
+// NotificationPreference stores whether a user wants in-app notifications of a given type.
+// A missing row means the notification type is enabled.
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey" json:"user_id"`
+	Type    string `gorm:"primaryKey;size:50" json:"type"`
+	Enabled bool   `gorm:"not null" json:"enabled"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
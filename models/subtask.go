@@ -1,12 +1,22 @@
 package models
 
-// Subtask represents a subtask or detail task attached to a Card.
+import "time"
+
+// Subtask represents a subtask or detail task attached to a Card. A subtask in a checklist is
+// one of its items; subtasks without a checklist are listed on the card on their own.
 type Subtask struct {
-	ID     uint   `gorm:"primaryKey" json:"id"`
-	Title  string `gorm:"not null" json:"title"`
-	IsDone bool   `gorm:"default:false" json:"is_done"`
-	CardID uint   `gorm:"not null" json:"card_id"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Title       string     `gorm:"not null" json:"title"`
+	IsDone      bool       `gorm:"default:false" json:"is_done"`
+	CardID      uint       `gorm:"not null" json:"card_id"`
+	ChecklistID *uint      `gorm:"index" json:"checklist_id"`
+	Position    int        `gorm:"not null;default:0" json:"position"` // Order within the checklist, or among the card's subtasks without one
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	Version     uint       `gorm:"not null;default:1" json:"version"` // Incremented on every update, used as the ETag
 
 	// The card this subtask belongs to.
-	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Card      Card       `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	Checklist *Checklist `gorm:"foreignKey:ChecklistID;constraint:OnDelete:CASCADE" json:"-"`
+	Assignee  *User      `gorm:"foreignKey:AssigneeID;constraint:OnDelete:SET NULL" json:"-"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TimeEntry records time a user spent on a card, either from a timer or logged manually.
+// A running timer has no EndedAt; its duration is computed when it is stopped.
+type TimeEntry struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	CardID          uint       `gorm:"not null;index" json:"card_id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	StartedAt       time.Time  `gorm:"not null" json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds int64      `gorm:"not null;default:0" json:"duration_seconds"`
+	Note            string     `json:"note"`
+	Manual          bool       `gorm:"not null;default:false" json:"manual"`
+	CreatedAt       time.Time  `json:"created_at"`
+
+	Card Card `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE" json:"-"`
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
+}
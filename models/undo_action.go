@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UndoAction records a kanban operation so that the user who performed it can reverse it
+// for a short time. Before and After are JSON snapshots of the affected row; an operation is
+// only undone while the row still matches After.
+type UndoAction struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Token     string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Entity    string     `gorm:"size:30;not null" json:"entity"` // "card", "list", "subtask", "card_assignee" or "card_label"
+	Action    string     `gorm:"size:20;not null" json:"action"` // "create", "update" or "delete"
+	Key       string     `gorm:"not null" json:"-"`              // JSON primary key of the row
+	Before    string     `gorm:"type:text" json:"-"`
+	After     string     `gorm:"type:text" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UndoneAt  *time.Time `json:"undone_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookEventCardCreated  = "card.created"
+	WebhookEventCardMoved    = "card.moved"
+	WebhookEventCardUpdated  = "card.updated"
+	WebhookEventCommentAdded = "comment.added"
+	WebhookEventMemberShared = "member.shared"
+	WebhookEventPing         = "ping" // Sent by the "send test event" endpoint only
+)
+
+// Webhook payload formats.
+const (
+	WebhookFormatJSON    = "json"    // The full signed event envelope
+	WebhookFormatSlack   = "slack"   // A chat message for a Slack incoming webhook
+	WebhookFormatDiscord = "discord" // A chat message for a Discord webhook
+)
+
+// WebhookEventTypes lists every event type a webhook can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventCardCreated,
+	WebhookEventCardMoved,
+	WebhookEventCardUpdated,
+	WebhookEventCommentAdded,
+	WebhookEventMemberShared,
+}
+
+// Webhook is an outgoing webhook subscription of a workspace. Events it subscribes to are
+// POSTed as JSON to its URL, signed with its secret.
+type Webhook struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint          `gorm:"not null;index" json:"workspace_id"`
+	URL         string        `gorm:"not null;size:2048" json:"url"`
+	Secret      string        `gorm:"not null;size:64" json:"-"` // HMAC-SHA256 signing key, only shown when created or rotated
+	Events      WebhookEvents `gorm:"type:text;not null" json:"events"`
+	Format      string        `gorm:"not null;size:20;default:json" json:"format"` // "json", "slack" or "discord"
+	Enabled     bool          `gorm:"not null;default:true" json:"enabled"`
+	CreatedByID uint          `gorm:"not null" json:"created_by_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+
+	Workspace Workspace `gorm:"foreignKey:WorkspaceID;constraint:OnDelete:CASCADE" json:"-"`
+	CreatedBy User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Subscribes reports whether the webhook subscribes to an event type.
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvents is the list of event types a webhook subscribes to, stored as JSON.
+type WebhookEvents []string
+
+// Value stores the event types as a JSON array.
+func (e WebhookEvents) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+// Scan reads the event types from a JSON array.
+func (e *WebhookEvents) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	case []byte:
+		return json.Unmarshal(v, e)
+	case nil:
+		*e = nil
+		return nil
+	}
+	return errors.New("unsupported type for webhook events")
+}
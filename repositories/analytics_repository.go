@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+)
+
+// GetAnalyticsLists retrieves every list of a workspace, archived ones included, without cards.
+func GetAnalyticsLists(workspaceID uint, lists *[]models.BoardList) error {
+	return database.DB.Where("workspace_id = ?", workspaceID).Order("id").Find(lists).Error
+}
+
+// GetAnalyticsCards retrieves the cards of a workspace created before the given time, archived
+// ones included, with their assignees.
+func GetAnalyticsCards(workspaceID uint, before time.Time, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ? AND cards.created_at < ?", workspaceID, before).
+		Preload("Assignees.User").
+		Order("cards.id").
+		Find(cards).Error
+}
+
+// GetCardTransitionsByWorkspace retrieves the list transitions before the given time of the
+// cards of a workspace that are not in the trash, ordered by card and then by time.
+func GetCardTransitionsByWorkspace(workspaceID uint, before time.Time, transitions *[]models.CardTransition) error {
+	return database.DB.
+		Joins("JOIN cards ON cards.id = card_transitions.card_id").
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ? AND cards.deleted_at IS NULL", workspaceID).
+		Where("card_transitions.moved_at < ?", before).
+		Order("card_transitions.card_id, card_transitions.moved_at, card_transitions.id").
+		Find(transitions).Error
+}
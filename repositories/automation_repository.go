@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateAutomationRule creates a new automation rule.
+func CreateAutomationRule(rule *models.AutomationRule) error {
+	return database.DB.Omit(clause.Associations).Create(rule).Error
+}
+
+// GetAutomationRulesByWorkspace retrieves the automation rules of a workspace, oldest first.
+func GetAutomationRulesByWorkspace(workspaceID uint, rules *[]models.AutomationRule) error {
+	return database.DB.Where("workspace_id = ?", workspaceID).Order("id ASC").Find(rules).Error
+}
+
+// GetEnabledAutomationRules retrieves the enabled rules of a workspace for a trigger, oldest first.
+func GetEnabledAutomationRules(workspaceID uint, trigger string, rules *[]models.AutomationRule) error {
+	return database.DB.
+		Where("workspace_id = ? AND trigger = ? AND enabled = ?", workspaceID, trigger, true).
+		Order("id ASC").
+		Find(rules).Error
+}
+
+// GetEnabledAutomationRulesByTrigger retrieves the enabled rules of every workspace for a trigger.
+func GetEnabledAutomationRulesByTrigger(trigger string, rules *[]models.AutomationRule) error {
+	return database.DB.Where("trigger = ? AND enabled = ?", trigger, true).Order("id ASC").Find(rules).Error
+}
+
+// GetAutomationRuleByID retrieves an automation rule by its ID.
+func GetAutomationRuleByID(id uint, rule *models.AutomationRule) error {
+	return database.DB.First(rule, id).Error
+}
+
+// UpdateAutomationRule updates an existing automation rule.
+func UpdateAutomationRule(rule *models.AutomationRule) error {
+	return database.DB.Omit(clause.Associations).Save(rule).Error
+}
+
+// DeleteAutomationRule deletes an automation rule together with its logs.
+func DeleteAutomationRule(id uint) error {
+	return database.DB.Delete(&models.AutomationRule{}, id).Error
+}
+
+// CreateAutomationLog records an execution of an automation rule.
+func CreateAutomationLog(entry *models.AutomationLog) error {
+	return database.DB.Omit(clause.Associations).Create(entry).Error
+}
+
+// GetAutomationLogs retrieves the latest execution logs of a workspace, newest first,
+// optionally only those of one rule (ruleID 0 returns all rules).
+func GetAutomationLogs(workspaceID, ruleID uint, limit int, logs *[]models.AutomationLog) error {
+	query := database.DB.Where("workspace_id = ?", workspaceID)
+	if ruleID != 0 {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	return query.Order("created_at DESC, id DESC").Limit(limit).Find(logs).Error
+}
+
+// HasAutomationRunSince reports whether a rule has run on a card, successfully or not, since the
+// given time. Skipped runs do not count.
+func HasAutomationRunSince(ruleID, cardID uint, since time.Time) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.AutomationLog{}).
+		Where("rule_id = ? AND card_id = ? AND status <> ? AND created_at >= ?", ruleID, cardID, models.AutomationStatusSkipped, since).
+		Count(&count).Error
+	return count > 0, err
+}
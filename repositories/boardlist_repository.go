@@ -1,8 +1,15 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateBoardList creates a new board list.
@@ -10,9 +17,17 @@ func CreateBoardList(list *models.BoardList) error {
 	return database.DB.Create(list).Error
 }
 
-// GetBoardListsByWorkspace retrieves all board lists for a given workspace.
-func GetBoardListsByWorkspace(workspaceID uint, lists *[]models.BoardList) error {
-	return database.DB.Where("workspace_id = ?", workspaceID).Preload("Cards").Find(lists).Error
+// GetBoardListsByWorkspace retrieves all board lists for a given workspace with their cards.
+// Archived lists and cards are left out unless includeArchived is set.
+func GetBoardListsByWorkspace(workspaceID uint, includeArchived bool, lists *[]models.BoardList) error {
+	query := database.DB.Where("workspace_id = ?", workspaceID)
+	if includeArchived {
+		return query.Preload("Cards").Find(lists).Error
+	}
+	return query.
+		Where("archived_at IS NULL").
+		Preload("Cards", "archived_at IS NULL").
+		Find(lists).Error
 }
 
 // GetBoardListByID retrieves a board list by its ID.
@@ -29,12 +44,109 @@ func GetWorkspaceIDByListID(listID uint) (uint, error) {
 	return boardList.WorkspaceID, nil
 }
 
-// UpdateBoardList updates an existing board list.
-func UpdateBoardList(list *models.BoardList) error {
-	return database.DB.Save(list).Error
+// CountCardsInList counts the cards of a list that are not archived, as counted against its
+// WIP limit.
+func CountCardsInList(listID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&models.Card{}).
+		Where("list_id = ? AND archived_at IS NULL", listID).
+		Count(&count).Error
+	return count, err
+}
+
+// ErrWIPLimitReached is returned by card writes that would take a list over its hard WIP limit.
+var ErrWIPLimitReached = errors.New("WIP limit reached")
+
+// WIPLimitError describes a list that cannot take more cards under its hard WIP limit.
+func WIPLimitError(list *models.BoardList) error {
+	return fmt.Errorf("%w: %q cannot hold more than %d cards", ErrWIPLimitReached, list.Title, list.WIPLimit)
+}
+
+// reserveListCapacity locks a list until the end of tx and returns a WIPLimitError if adding
+// cards would take it over a hard WIP limit. Card writes call it in their transaction, so that
+// concurrent writes to the same list are checked one after the other.
+func reserveListCapacity(tx *gorm.DB, listID uint, adding int) error {
+	if adding <= 0 {
+		return nil
+	}
+	var list models.BoardList
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&list, listID).Error; err != nil {
+		return err
+	}
+	if list.WIPLimit <= 0 || list.WIPLimitMode != models.WIPLimitHard {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Card{}).Where("list_id = ? AND archived_at IS NULL", listID).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count)+adding > list.WIPLimit {
+		return WIPLimitError(&list)
+	}
+	return nil
 }
 
-// DeleteBoardList deletes a board list by its ID.
+// reserveListCapacityForMove is reserveListCapacity for moving the cards matched by the where
+// condition into a list. Archived cards and cards already in the list are not counted.
+func reserveListCapacityForMove(tx *gorm.DB, listID uint, where string, args ...interface{}) error {
+	var entering int64
+	if err := tx.Model(&models.Card{}).
+		Where("list_id <> ? AND archived_at IS NULL", listID).
+		Where(where, args...).
+		Count(&entering).Error; err != nil {
+		return err
+	}
+	return reserveListCapacity(tx, listID, int(entering))
+}
+
+// UpdateBoardList writes the given columns of a board list if it is still at the version it was
+// loaded with, incrementing the version. It returns false if someone else has updated it since.
+func UpdateBoardList(list *models.BoardList, columns []string) (bool, error) {
+	expected := list.Version
+	list.Version++
+	list.UpdatedAt = time.Now()
+	ok, err := updateVersioned(list, expected, append(columns, "version", "updated_at"))
+	if !ok {
+		list.Version = expected
+	}
+	return ok, err
+}
+
+// DeleteBoardList moves a board list and its cards to the trash. They share the same deletion
+// time, so restoring the list brings back exactly the cards deleted with it.
 func DeleteBoardList(id uint) error {
-	return database.DB.Delete(&models.BoardList{}, id).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.Card{}).Where("list_id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.BoardList{}).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+}
+
+// SetBoardListCompletedAt sets or, with nil, clears the completion time of a board list.
+func SetBoardListCompletedAt(id uint, completedAt *time.Time) error {
+	return database.DB.Model(&models.BoardList{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"completed_at": completedAt, "updated_at": time.Now(), "version": bumpVersion}).Error
+}
+
+// SetBoardListArchivedAt archives a board list at the given time or, with nil, unarchives it.
+// The archived state of its cards is left untouched.
+func SetBoardListArchivedAt(id uint, archivedAt *time.Time) error {
+	return database.DB.Model(&models.BoardList{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"archived_at": archivedAt, "updated_at": time.Now(), "version": bumpVersion}).Error
+}
+
+// GetArchivedBoardListsByWorkspace retrieves the archived lists of a workspace with all their
+// cards, most recently archived first.
+func GetArchivedBoardListsByWorkspace(workspaceID uint, lists *[]models.BoardList) error {
+	return database.DB.
+		Where("workspace_id = ? AND archived_at IS NOT NULL", workspaceID).
+		Order("archived_at DESC").
+		Preload("Cards").
+		Find(lists).Error
 }
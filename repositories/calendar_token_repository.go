@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetCalendarToken retrieves a user's calendar token for a workspace, or their personal token if workspaceID is nil.
+func GetCalendarToken(userID uint, workspaceID *uint, token *models.CalendarToken) error {
+	query := database.DB.Where("user_id = ?", userID)
+	if workspaceID == nil {
+		query = query.Where("workspace_id IS NULL")
+	} else {
+		query = query.Where("workspace_id = ?", *workspaceID)
+	}
+	return query.First(token).Error
+}
+
+// GetCalendarTokenByToken retrieves a calendar token by its secret value.
+func GetCalendarTokenByToken(value string, token *models.CalendarToken) error {
+	return database.DB.Where("token = ?", value).First(token).Error
+}
+
+// ReplaceCalendarToken deletes any existing token of the same user and feed and stores the new one.
+func ReplaceCalendarToken(token *models.CalendarToken) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("user_id = ?", token.UserID)
+		if token.WorkspaceID == nil {
+			query = query.Where("workspace_id IS NULL")
+		} else {
+			query = query.Where("workspace_id = ?", *token.WorkspaceID)
+		}
+		if err := query.Delete(&models.CalendarToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
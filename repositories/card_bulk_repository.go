@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bulk card operations.
+const (
+	BulkCardMove        = "move"
+	BulkCardAssign      = "assign"
+	BulkCardUnassign    = "unassign"
+	BulkCardLabel       = "label"
+	BulkCardSetDeadline = "set_deadline"
+	BulkCardArchive     = "archive"
+	BulkCardDelete      = "delete"
+)
+
+// IsValidBulkCardOperation reports whether op is a supported bulk card operation.
+func IsValidBulkCardOperation(op string) bool {
+	switch op {
+	case BulkCardMove, BulkCardAssign, BulkCardUnassign, BulkCardLabel, BulkCardSetDeadline, BulkCardArchive, BulkCardDelete:
+		return true
+	}
+	return false
+}
+
+// BulkCardChange holds the target of a bulk card operation. Only the field used by the
+// operation is read: ListID for move, UserID for assign and unassign, LabelID for label and
+// Deadline for set_deadline, where nil clears the deadline.
+type BulkCardChange struct {
+	ListID   uint
+	UserID   uint
+	LabelID  uint
+	Deadline *time.Time
+}
+
+// GetCardsWithListByIDs retrieves the cards with the given IDs with their list preloaded.
+func GetCardsWithListByIDs(ids []uint, cards *[]models.Card) error {
+	return database.DB.Where("id IN ?", ids).Preload("List").Find(cards).Error
+}
+
+// GetCardIDsAssignedToUser returns which of the given cards a user is assigned to.
+func GetCardIDsAssignedToUser(userID uint, cardIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := database.DB.Model(&models.CardAssignee{}).
+		Where("user_id = ? AND card_id IN ?", userID, cardIDs).
+		Pluck("card_id", &ids).Error
+	return ids, err
+}
+
+// GetCardIDsWithLabel returns which of the given cards already have a label.
+func GetCardIDsWithLabel(labelID uint, cardIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := database.DB.Model(&models.CardLabel{}).
+		Where("label_id = ? AND card_id IN ?", labelID, cardIDs).
+		Pluck("card_id", &ids).Error
+	return ids, err
+}
+
+// ApplyBulkCardChange applies a bulk operation to the given cards in a single transaction.
+// Assigning and labelling skip cards that already have the assignee or label. A move fails
+// with an error wrapping ErrWIPLimitReached if it would take the list over its hard WIP limit.
+func ApplyBulkCardChange(op string, cardIDs []uint, change BulkCardChange, now time.Time) error {
+	if len(cardIDs) == 0 {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		cards := tx.Model(&models.Card{}).Where("id IN ?", cardIDs)
+
+		switch op {
+		case BulkCardMove:
+			if err := reserveListCapacityForMove(tx, change.ListID, "id IN ?", cardIDs); err != nil {
+				return err
+			}
+			if err := recordCardMoves(tx, change.ListID, now, "id IN ?", cardIDs); err != nil {
+				return err
+			}
+			return cards.Updates(map[string]interface{}{"list_id": change.ListID, "updated_at": now, "version": bumpVersion}).Error
+		case BulkCardSetDeadline:
+			return cards.Updates(map[string]interface{}{"deadline": change.Deadline, "updated_at": now, "version": bumpVersion}).Error
+		case BulkCardArchive:
+			return cards.Where("archived_at IS NULL").Updates(map[string]interface{}{"archived_at": now, "updated_at": now, "version": bumpVersion}).Error
+		case BulkCardDelete:
+			return cards.Update("deleted_at", now).Error
+		case BulkCardUnassign:
+			return tx.Where("user_id = ? AND card_id IN ?", change.UserID, cardIDs).Delete(&models.CardAssignee{}).Error
+		case BulkCardAssign:
+			assignees := make([]models.CardAssignee, len(cardIDs))
+			for i, id := range cardIDs {
+				assignees[i] = models.CardAssignee{CardID: id, UserID: change.UserID}
+			}
+			return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&assignees).Error
+		case BulkCardLabel:
+			labels := make([]models.CardLabel, len(cardIDs))
+			for i, id := range cardIDs {
+				labels[i] = models.CardLabel{CardID: id, LabelID: change.LabelID, CreatedAt: now}
+			}
+			return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&labels).Error
+		}
+		return nil
+	})
+}
@@ -1,8 +1,13 @@
 package repositories
 
 import (
+	"time"
+
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateCardComment creates a new card comment.
@@ -10,25 +15,110 @@ func CreateCardComment(comment *models.CardComment) error {
 	return database.DB.Create(comment).Error
 }
 
-// GetCommentsByCardID retrieves all card comments for a given card.
+// GetCommentsByCardID retrieves all card comments for a given card, oldest first,
+// including deleted comments so that threads can show placeholders.
 func GetCommentsByCardID(cardID uint, comments *[]models.CardComment) error {
 	return database.DB.
 		Where("card_id = ?", cardID).
+		Order("created_at ASC, id ASC").
 		Preload("User").
+		Preload("Mentions.User").
+		Preload("Reactions").
 		Find(comments).Error
 }
 
 // GetCardCommentByID retrieves a card comment by its ID.
 func GetCardCommentByID(id uint, comment *models.CardComment) error {
-	return database.DB.Preload("User").First(comment, id).Error
+	return database.DB.
+		Preload("User").
+		Preload("Mentions.User").
+		Preload("Reactions").
+		First(comment, id).Error
 }
 
 // UpdateCardComment updates an existing card comment.
 func UpdateCardComment(comment *models.CardComment) error {
-	return database.DB.Save(comment).Error
+	return database.DB.Omit(clause.Associations).Save(comment).Error
+}
+
+// UpdateCardCommentWithRevision stores the previous text of a comment as a revision
+// and saves the edited comment in a single transaction.
+func UpdateCardCommentWithRevision(comment *models.CardComment, revision *models.CommentRevision) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Save(comment).Error
+	})
+}
+
+// GetCommentRevisions retrieves the revisions of a comment, newest first.
+func GetCommentRevisions(commentID uint, revisions *[]models.CommentRevision) error {
+	return database.DB.
+		Where("comment_id = ?", commentID).
+		Order("created_at DESC, id DESC").
+		Find(revisions).Error
 }
 
-// DeleteCardComment deletes a card comment by its ID.
+// DeleteCardComment soft-deletes a card comment by its ID. The row is kept as a thread
+// placeholder, while its text, mentions, reactions and revisions are removed.
 func DeleteCardComment(id uint) error {
-	return database.DB.Delete(&models.CardComment{}, id).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.CardComment{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{"comment": "", "deleted_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("comment_id = ?", id).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", id).Delete(&models.CommentReaction{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("comment_id = ?", id).Delete(&models.CommentRevision{}).Error
+	})
+}
+
+// ToggleCommentReaction adds the reaction if the user has not reacted with that emoji yet,
+// and removes it otherwise. It returns true if the reaction was added.
+func ToggleCommentReaction(reaction *models.CommentReaction) (bool, error) {
+	added := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("comment_id = ? AND user_id = ? AND emoji = ?", reaction.CommentID, reaction.UserID, reaction.Emoji).
+			Delete(&models.CommentReaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		added = true
+		return tx.Create(reaction).Error
+	})
+	return added, err
+}
+
+// GetCommentsOnAssignedCardsSince retrieves comments written by other users since the given time
+// on cards the user is assigned to in workspaces they still belong to, preloading the author and card.
+func GetCommentsOnAssignedCardsSince(userID uint, since time.Time, comments *[]models.CardComment) error {
+	collabQuery := database.DB.Table("workspace_users").Select("workspace_id").Where("user_id = ?", userID)
+
+	return database.DB.
+		Joins("JOIN card_assignees ON card_assignees.card_id = card_comments.card_id").
+		Joins("JOIN cards ON cards.id = card_comments.card_id AND cards.deleted_at IS NULL").
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Joins("JOIN workspaces ON workspaces.id = board_lists.workspace_id").
+		Where("card_assignees.user_id = ? AND card_comments.user_id <> ? AND card_comments.created_at > ?", userID, userID, since).
+		Where("workspaces.owner_id = ? OR workspaces.id IN (?)", userID, collabQuery).
+		Where("card_comments.deleted_at IS NULL").
+		Order("card_comments.created_at ASC").
+		Preload("User").
+		Preload("Card").
+		Find(comments).Error
 }
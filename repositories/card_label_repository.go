@@ -3,28 +3,41 @@ package repositories
 import (
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
 )
 
-// CreateCardLabel creates a new card label.
+// CreateCardLabel adds a catalog label to a card.
 func CreateCardLabel(label *models.CardLabel) error {
-	return database.DB.Create(label).Error
+	return database.DB.Omit(clause.Associations).Create(label).Error
 }
 
 // GetLabelsByCardID retrieves all card labels for a given card.
 func GetLabelsByCardID(cardID uint, labels *[]models.CardLabel) error {
 	return database.DB.
 		Where("card_id = ?", cardID).
+		Preload("Label").
 		Find(labels).Error
 }
 
 // GetCardLabelByID retrieves a card label by its ID.
 func GetCardLabelByID(id uint, label *models.CardLabel) error {
-	return database.DB.First(label, id).Error
+	return database.DB.Preload("Label").First(label, id).Error
+}
+
+// IsLabelOnCard checks whether a catalog label is already on a card.
+func IsLabelOnCard(cardID, labelID uint) (bool, error) {
+	var count int64
+	err := database.DB.
+		Model(&models.CardLabel{}).
+		Where("card_id = ? AND label_id = ?", cardID, labelID).
+		Count(&count).Error
+	return count > 0, err
 }
 
 // UpdateCardLabel updates an existing card label.
 func UpdateCardLabel(label *models.CardLabel) error {
-	return database.DB.Save(label).Error
+	return database.DB.Omit(clause.Associations).Save(label).Error
 }
 
 // DeleteCardLabel deletes a card label by its ID.
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+)
+
+// CreateCardLink creates a link between two cards.
+func CreateCardLink(link *models.CardLink) error {
+	return database.DB.Create(link).Error
+}
+
+// GetCardLinkByID retrieves a card link by its ID.
+func GetCardLinkByID(id uint, link *models.CardLink) error {
+	return database.DB.First(link, id).Error
+}
+
+// DeleteCardLink deletes a card link by its ID.
+func DeleteCardLink(id uint) error {
+	return database.DB.Delete(&models.CardLink{}, id).Error
+}
+
+// GetCardLinksByCard retrieves the links from and to a card, preloading both cards. Cards in the
+// trash are not preloaded and are left zero.
+func GetCardLinksByCard(cardID uint, links *[]models.CardLink) error {
+	return database.DB.
+		Preload("Card").
+		Preload("LinkedCard").
+		Where("card_id = ? OR linked_card_id = ?", cardID, cardID).
+		Order("created_at ASC").
+		Find(links).Error
+}
+
+// CardLinkExists reports whether a link of the given type exists from cardID to linkedCardID.
+func CardLinkExists(cardID, linkedCardID uint, linkType string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.CardLink{}).
+		Where("card_id = ? AND linked_card_id = ? AND type = ?", cardID, linkedCardID, linkType).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetCardIDsBlockedBy returns the IDs of the cards that any of the given cards block.
+func GetCardIDsBlockedBy(cardIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := database.DB.Model(&models.CardLink{}).
+		Where("card_id IN ? AND type = ?", cardIDs, models.CardLinkBlocks).
+		Pluck("linked_card_id", &ids).Error
+	return ids, err
+}
+
+// GetOpenBlockers retrieves the cards that block a card and are not completed yet, leaving out
+// cards in the trash.
+func GetOpenBlockers(cardID uint, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN card_links ON card_links.card_id = cards.id").
+		Where("card_links.linked_card_id = ? AND card_links.type = ?", cardID, models.CardLinkBlocks).
+		Where("cards.completed_at IS NULL").
+		Find(cards).Error
+}
@@ -1,45 +1,358 @@
 package repositories
 
 import (
+	"errors"
+	"slices"
+	"time"
+
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // === Card Functions ===
 
-// CreateCard creates a new card.
+// ErrCardAlreadyCompleted is returned by CompleteCard for a card that is already completed.
+var ErrCardAlreadyCompleted = errors.New("card is already completed")
+
+// CreateCard creates a new card. It returns an error wrapping ErrWIPLimitReached if its list
+// is at its hard WIP limit.
 func CreateCard(card *models.Card) error {
-	return database.DB.Create(card).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if card.ArchivedAt == nil {
+			if err := reserveListCapacity(tx, card.ListID, 1); err != nil {
+				return err
+			}
+		}
+		return tx.Create(card).Error
+	})
 }
 
-// GetCardsByListID retrieves cards for a given list.
-func GetCardsByListID(listID uint, cards *[]models.Card) error {
-	return database.DB.Where("list_id = ?", listID).
-		Preload("Subtasks").
+// notArchivedCard restricts a query joined with board_lists to cards that are not archived
+// and are not in an archived list.
+const notArchivedCard = "cards.archived_at IS NULL AND board_lists.archived_at IS NULL"
+
+// withCardDetails preloads the associations returned with a card.
+func withCardDetails(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Subtasks", byPosition).
+		Preload("Checklists", byPosition).
+		Preload("Checklists.Items", byPosition).
 		Preload("Assignees.User").
 		Preload("Attachments").
-		Preload("Labels").
+		Preload("Labels.Label").
 		Preload("Comments.User").
-		Find(cards).Error
+		Preload("CustomFields.Field")
+}
+
+// GetCardsByListID retrieves cards for a given list, leaving out archived cards unless
+// includeArchived is set.
+func GetCardsByListID(listID uint, includeArchived bool, cards *[]models.Card) error {
+	query := database.DB.Where("list_id = ?", listID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	return withCardDetails(query).Find(cards).Error
 }
 
 // GetCardByID retrieves a card by its ID, preloading its associations.
 func GetCardByID(id uint, card *models.Card) error {
-	return database.DB.
-		Preload("Subtasks").
-		Preload("Assignees.User").
-		Preload("Attachments").
-		Preload("Labels").
-		Preload("Comments.User").
-		First(card, id).Error
+	return withCardDetails(database.DB).First(card, id).Error
 }
 
-// UpdateCard updates an existing card.
-func UpdateCard(card *models.Card) error {
-	return database.DB.Save(card).Error
+// UpdateCard writes the given columns of a card if it is still at the version it was loaded
+// with, incrementing the version. It returns false, leaving the card unchanged, if someone else
+// has updated the card since. A change of list is recorded in the card's list history, and
+// fails with an error wrapping ErrWIPLimitReached if the new list is at its hard WIP limit.
+func UpdateCard(card *models.Card, columns []string) (bool, error) {
+	expected := card.Version
+	card.Version++
+	card.UpdatedAt = time.Now()
+	var ok bool
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if slices.Contains(columns, "list_id") {
+			if err := reserveListCapacityForMove(tx, card.ListID, "id = ? AND version = ?", card.ID, expected); err != nil {
+				return err
+			}
+			if err := recordCardMoves(tx, card.ListID, card.UpdatedAt, "id = ? AND version = ?", card.ID, expected); err != nil {
+				return err
+			}
+		}
+		var err error
+		ok, err = updateVersionedTx(tx, card, expected, append(columns, "version", "updated_at"))
+		return err
+	})
+	if !ok {
+		card.Version = expected
+	}
+	return ok, err
 }
 
-// DeleteCard deletes a card by its ID.
+// DeleteCard moves a card to the trash.
 func DeleteCard(id uint) error {
 	return database.DB.Delete(&models.Card{}, id).Error
 }
+
+// GetCardsWithDeadlineBefore retrieves all open cards whose deadline is at or before the given time,
+// preloading their list and assignees. Completed and archived cards are left out.
+func GetCardsWithDeadlineBefore(until time.Time, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("cards.deadline IS NOT NULL AND cards.deadline <= ?", until).
+		Where("cards.completed_at IS NULL AND " + notArchivedCard).
+		Preload("List").
+		Preload("Assignees").
+		Find(cards).Error
+}
+
+// GetOverdueCardsByWorkspace retrieves the open cards of a workspace whose deadline has passed.
+func GetOverdueCardsByWorkspace(workspaceID uint, now time.Time, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ? AND cards.deadline IS NOT NULL AND cards.deadline < ?", workspaceID, now).
+		Where("cards.completed_at IS NULL AND " + notArchivedCard).
+		Order("cards.deadline ASC").
+		Preload("Assignees.User").
+		Preload("Labels.Label").
+		Find(cards).Error
+}
+
+// GetCardWithListByID retrieves a card by its ID with only its list preloaded.
+func GetCardWithListByID(id uint, card *models.Card) error {
+	return database.DB.Preload("List").First(card, id).Error
+}
+
+// GetCardsAssignedToUser retrieves every open card a user is assigned to in the workspaces they can
+// still access, ordered by deadline, preloading its list, workspace, labels and subtasks.
+// Completed and archived cards are left out.
+func GetCardsAssignedToUser(userID uint, cards *[]models.Card) error {
+	collabQuery := database.DB.Table("workspace_users").Select("workspace_id").Where("user_id = ?", userID)
+
+	return database.DB.
+		Joins("JOIN card_assignees ON card_assignees.card_id = cards.id").
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Joins("JOIN workspaces ON workspaces.id = board_lists.workspace_id").
+		Where("card_assignees.user_id = ?", userID).
+		Where("workspaces.owner_id = ? OR workspaces.id IN (?)", userID, collabQuery).
+		Where("cards.completed_at IS NULL AND " + notArchivedCard).
+		Order("cards.deadline ASC NULLS LAST, cards.id ASC").
+		Preload("List.Workspace").
+		Preload("Labels.Label").
+		Preload("Subtasks").
+		Find(cards).Error
+}
+
+// GetAssignedCardsByDeadlineRange retrieves the cards a user is assigned to in the workspaces they
+// can access with a deadline in [from, to), ordered by deadline, preloading their list, workspace,
+// labels and subtasks. Like GetCardsByWorkspaceDeadlineRange, it keeps completed cards and leaves
+// archived ones out.
+func GetAssignedCardsByDeadlineRange(userID uint, from, to time.Time, cards *[]models.Card) error {
+	collabQuery := database.DB.Table("workspace_users").Select("workspace_id").Where("user_id = ?", userID)
+
+	return database.DB.
+		Joins("JOIN card_assignees ON card_assignees.card_id = cards.id").
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Joins("JOIN workspaces ON workspaces.id = board_lists.workspace_id").
+		Where("card_assignees.user_id = ?", userID).
+		Where("workspaces.owner_id = ? OR workspaces.id IN (?)", userID, collabQuery).
+		Where(notArchivedCard).
+		Where("cards.deadline >= ? AND cards.deadline < ?", from, to).
+		Order("cards.deadline ASC, cards.id ASC").
+		Preload("List.Workspace").
+		Preload("Labels.Label").
+		Preload("Subtasks").
+		Find(cards).Error
+}
+
+// GetCardsByWorkspaceDeadlineRange retrieves the cards of a workspace with a deadline in [from, to),
+// ordered by deadline, preloading their list, workspace, labels and subtasks. Archived cards are left out.
+func GetCardsByWorkspaceDeadlineRange(workspaceID uint, from, to time.Time, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ?", workspaceID).
+		Where(notArchivedCard).
+		Where("cards.deadline >= ? AND cards.deadline < ?", from, to).
+		Order("cards.deadline ASC, cards.id ASC").
+		Preload("List.Workspace").
+		Preload("Labels.Label").
+		Preload("Subtasks").
+		Find(cards).Error
+}
+
+// CompleteCard marks a card as completed. If next is not nil it is created in the same
+// transaction, together with its checklists, subtasks, assignees, labels and custom field
+// values, and recorded as the card's next occurrence. It returns an error wrapping
+// ErrWIPLimitReached if next's list is at its hard WIP limit, and ErrCardAlreadyCompleted if
+// the card was completed in the meantime, so that two completions never both create a next
+// occurrence.
+func CompleteCard(card *models.Card, completedAt time.Time, next *models.Card) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Card{}).Where("id = ? AND completed_at IS NULL", card.ID).
+			Updates(map[string]interface{}{"completed_at": completedAt, "updated_at": completedAt, "version": bumpVersion})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrCardAlreadyCompleted
+		}
+
+		if next != nil {
+			if err := reserveListCapacity(tx, next.ListID, 1); err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Create(next).Error; err != nil {
+				return err
+			}
+			for i := range next.Checklists {
+				checklist := &next.Checklists[i]
+				checklist.CardID = next.ID
+				if err := tx.Omit(clause.Associations).Create(checklist).Error; err != nil {
+					return err
+				}
+				for j := range checklist.Items {
+					checklist.Items[j].CardID = next.ID
+					checklist.Items[j].ChecklistID = &checklist.ID
+				}
+				if len(checklist.Items) > 0 {
+					if err := tx.Omit(clause.Associations).Create(&checklist.Items).Error; err != nil {
+						return err
+					}
+				}
+			}
+			for i := range next.Subtasks {
+				next.Subtasks[i].CardID = next.ID
+			}
+			for i := range next.Assignees {
+				next.Assignees[i].CardID = next.ID
+			}
+			for i := range next.Labels {
+				next.Labels[i].CardID = next.ID
+			}
+			for i := range next.CustomFields {
+				next.CustomFields[i].CardID = next.ID
+			}
+			if len(next.Subtasks) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&next.Subtasks).Error; err != nil {
+					return err
+				}
+			}
+			if len(next.Assignees) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&next.Assignees).Error; err != nil {
+					return err
+				}
+			}
+			if len(next.Labels) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&next.Labels).Error; err != nil {
+					return err
+				}
+			}
+			if len(next.CustomFields) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&next.CustomFields).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&models.Card{}).Where("id = ?", card.ID).Update("next_occurrence_id", next.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		card.CompletedAt = &completedAt
+		card.UpdatedAt = completedAt
+		card.Version++
+		if next != nil {
+			card.NextOccurrenceID = &next.ID
+		}
+		return nil
+	})
+}
+
+// SetCardCompletedAt sets or, with nil, clears the completion time of a card.
+func SetCardCompletedAt(id uint, completedAt *time.Time) error {
+	return database.DB.Model(&models.Card{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"completed_at": completedAt, "updated_at": time.Now(), "version": bumpVersion}).Error
+}
+
+// SetCardArchivedAt archives a card at the given time or, with nil, unarchives it. Unarchiving
+// returns an error wrapping ErrWIPLimitReached if the card's list is at its hard WIP limit.
+func SetCardArchivedAt(id uint, archivedAt *time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if archivedAt == nil {
+			var card models.Card
+			if err := tx.Select("list_id", "archived_at").First(&card, id).Error; err != nil {
+				return err
+			}
+			if card.ArchivedAt != nil {
+				if err := reserveListCapacity(tx, card.ListID, 1); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&models.Card{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"archived_at": archivedAt, "updated_at": time.Now(), "version": bumpVersion}).Error
+	})
+}
+
+// MoveCard moves a card to another list, recording the move in its list history. It returns an
+// error wrapping ErrWIPLimitReached if the list is at its hard WIP limit.
+func MoveCard(id, listID uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := reserveListCapacityForMove(tx, listID, "id = ?", id); err != nil {
+			return err
+		}
+		if err := recordCardMoves(tx, listID, now, "id = ?", id); err != nil {
+			return err
+		}
+		return tx.Model(&models.Card{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"list_id": listID, "updated_at": now, "version": bumpVersion}).Error
+	})
+}
+
+// RevertCard writes back a previous state of a card, including soft-deleted fields, as undo
+// does. Putting the card back in a list is recorded in its list history, and returns an error
+// wrapping ErrWIPLimitReached if the list is at its hard WIP limit.
+func RevertCard(card *models.Card) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if card.ArchivedAt == nil && !card.DeletedAt.Valid {
+			var entering int64
+			if err := tx.Model(&models.Card{}).
+				Where("id = ? AND (list_id <> ? OR archived_at IS NOT NULL)", card.ID, card.ListID).
+				Count(&entering).Error; err != nil {
+				return err
+			}
+			if err := reserveListCapacity(tx, card.ListID, int(entering)); err != nil {
+				return err
+			}
+		}
+		if err := recordCardMoves(tx, card.ListID, time.Now(), "id = ?", card.ID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Omit(clause.Associations).Save(card).Error
+	})
+}
+
+// recordCardMoves records the cards matched by the where condition entering a list at movedAt.
+// Cards already in that list are skipped. It must run before the cards' list_id is changed.
+func recordCardMoves(tx *gorm.DB, listID uint, movedAt time.Time, where string, args ...interface{}) error {
+	return tx.Exec(`
+		INSERT INTO card_transitions (card_id, from_list_id, to_list_id, moved_at)
+		SELECT id, list_id, ?, ? FROM cards
+		WHERE list_id <> ? AND `+where,
+		append([]interface{}{listID, movedAt, listID}, args...)...,
+	).Error
+}
+
+// GetArchivedCardsByWorkspace retrieves the archived cards of a workspace that are in lists
+// which are not archived themselves, most recently archived first.
+func GetArchivedCardsByWorkspace(workspaceID uint, cards *[]models.Card) error {
+	return database.DB.
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ?", workspaceID).
+		Where("cards.archived_at IS NOT NULL AND board_lists.archived_at IS NULL").
+		Order("cards.archived_at DESC").
+		Preload("Assignees.User").
+		Preload("Labels.Label").
+		Find(cards).Error
+}
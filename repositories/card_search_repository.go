@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+)
+
+// Card search sort fields.
+const (
+	CardSortCreatedAt = "created_at"
+	CardSortUpdatedAt = "updated_at"
+	CardSortDeadline  = "deadline"
+	CardSortTitle     = "title"
+	CardSortPriority  = "priority"
+	// CardSortCustomField sorts by the value of CardSearchFilter.SortField.
+	CardSortCustomField = "custom_field"
+)
+
+// Custom field filter operators.
+const (
+	CustomFieldOpEquals = "eq"    // The value equals, or for multi_select includes, Value
+	CustomFieldOpMin    = "min"   // Number or date value at or after Value
+	CustomFieldOpMax    = "max"   // Number or date value at or before Value
+	CustomFieldOpSet    = "set"   // The card has a value
+	CustomFieldOpUnset  = "unset" // The card has no value
+)
+
+// Subtask completion filters.
+const (
+	SubtasksComplete   = "complete"
+	SubtasksIncomplete = "incomplete"
+	SubtasksNone       = "none"
+)
+
+// cardSortExpressions maps each sort field to the SQL expression cards are ordered by.
+// Every expression is castable to and from text so it can be stored in a cursor.
+var cardSortExpressions = map[string]struct {
+	expr string
+	cast string
+}{
+	CardSortCreatedAt: {"cards.created_at", "timestamptz"},
+	CardSortUpdatedAt: {"cards.updated_at", "timestamptz"},
+	CardSortDeadline:  {"COALESCE(cards.deadline, 'infinity'::timestamptz)", "timestamptz"},
+	CardSortTitle:     {"LOWER(cards.title)", "text"},
+	CardSortPriority:  {"CASE cards.priority WHEN 'urgent' THEN 4 WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END", "int"},
+}
+
+// IsValidCardSort reports whether sort is a supported card search sort field.
+func IsValidCardSort(sort string) bool {
+	_, ok := cardSortExpressions[sort]
+	return ok
+}
+
+// customFieldScalar extracts a custom field value stored as a JSON scalar as text.
+const customFieldScalar = "(%s.value::jsonb #>> '{}')"
+
+// customFieldSortExpression returns the SQL expression and cursor cast used to sort by a
+// custom field of the given type, joined as sort_value. Cards without a value sort last
+// for numbers and dates, as unchecked for checkboxes and as empty text otherwise.
+func customFieldSortExpression(fieldType string) (string, string) {
+	scalar := fmt.Sprintf(customFieldScalar, "sort_value")
+	switch fieldType {
+	case models.CustomFieldNumber, models.CustomFieldUser:
+		return fmt.Sprintf("COALESCE(%s::float8, 'Infinity'::float8)", scalar), "float8"
+	case models.CustomFieldDate:
+		return fmt.Sprintf("COALESCE(%s::timestamptz, 'infinity'::timestamptz)", scalar), "timestamptz"
+	case models.CustomFieldCheckbox:
+		return fmt.Sprintf("COALESCE(%s::boolean, false)", scalar), "boolean"
+	}
+	return fmt.Sprintf("COALESCE(LOWER(%s), '')", scalar), "text"
+}
+
+// cardSortExpression returns the sort expression and cursor cast of a search.
+func cardSortExpression(filter CardSearchFilter) struct{ expr, cast string } {
+	sort := cardSortExpressions[filter.Sort]
+	if filter.Sort == CardSortCustomField {
+		sort.expr, sort.cast = customFieldSortExpression(filter.SortFieldType)
+	}
+	return sort
+}
+
+// CardSortKeyType returns the Postgres type a cursor's sort key is cast to for a search:
+// "timestamptz", "text", "int", "float8" or "boolean".
+func CardSortKeyType(filter CardSearchFilter) string {
+	return cardSortExpression(filter).cast
+}
+
+// CustomFieldFilter matches cards by the value of one custom field.
+type CustomFieldFilter struct {
+	FieldID uint
+	Type    string // The field's type, one of the models.CustomField constants
+	Op      string // One of the CustomFieldOp constants
+	// Value is the canonical JSON value for CustomFieldOpEquals (a one-option array for
+	// multi_select), and a number or RFC3339 time for CustomFieldOpMin and CustomFieldOpMax.
+	Value string
+}
+
+// customFieldCondition returns the SQL condition on a custom field value joined as v, and its arguments.
+func customFieldCondition(filter CustomFieldFilter) (string, []interface{}) {
+	scalar := fmt.Sprintf(customFieldScalar, "v")
+	cast := "float8"
+	if filter.Type == models.CustomFieldDate {
+		cast = "timestamptz"
+	}
+
+	switch filter.Op {
+	case CustomFieldOpEquals:
+		switch filter.Type {
+		case models.CustomFieldText:
+			return fmt.Sprintf("LOWER(%s) = LOWER(?::jsonb #>> '{}')", scalar), []interface{}{filter.Value}
+		case models.CustomFieldMultiSelect:
+			return "v.value::jsonb @> ?::jsonb", []interface{}{filter.Value}
+		}
+		return "v.value::jsonb = ?::jsonb", []interface{}{filter.Value}
+	case CustomFieldOpMin:
+		return fmt.Sprintf("%s::%s >= CAST(? AS %s)", scalar, cast, cast), []interface{}{filter.Value}
+	case CustomFieldOpMax:
+		return fmt.Sprintf("%s::%s <= CAST(? AS %s)", scalar, cast, cast), []interface{}{filter.Value}
+	}
+	return "TRUE", nil
+}
+
+// CardSearchFilter describes a workspace card search. Zero values disable a filter.
+type CardSearchFilter struct {
+	WorkspaceID  uint
+	Text         string // Full-text query over title, description and comments
+	ListIDs      []uint
+	LabelIDs     []uint   // Cards with any of these catalog labels
+	AssigneeIDs  []uint   // Cards assigned to any of these users
+	Priorities   []string // Cards with any of these priorities
+	DeadlineFrom *time.Time
+	DeadlineTo   *time.Time
+	Overdue      bool
+	Subtasks     string // SubtasksComplete, SubtasksIncomplete or SubtasksNone
+	Completed    *bool  // Only completed cards when true, only open cards when false
+	Archived     bool   // Include archived cards and cards in archived lists
+	CustomFields []CustomFieldFilter
+	Now          time.Time
+
+	Sort          string // One of the CardSort constants
+	SortField     uint   // CardSortCustomField: the custom field to sort by
+	SortFieldType string // CardSortCustomField: the type of that field
+	Descending    bool
+	// AfterKey and AfterID are the sort key and ID of the last card of the previous page.
+	AfterKey string
+	AfterID  uint
+	Limit    int
+}
+
+// CardSearchHit is a matching card ID with the text form of its sort key, used for cursors.
+type CardSearchHit struct {
+	ID      uint
+	SortKey string
+}
+
+// SearchCards returns up to filter.Limit matching card IDs in sort order, or every match if
+// filter.Limit is not positive.
+func SearchCards(filter CardSearchFilter) ([]CardSearchHit, error) {
+	sort := cardSortExpression(filter)
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	query := database.DB.
+		Table("cards").
+		Select(fmt.Sprintf("cards.id AS id, (%s)::text AS sort_key", sort.expr)).
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ? AND cards.deleted_at IS NULL", filter.WorkspaceID)
+	if filter.Sort == CardSortCustomField {
+		query = query.Joins("LEFT JOIN card_custom_field_values AS sort_value ON sort_value.card_id = cards.id AND sort_value.field_id = ?", filter.SortField)
+	}
+
+	if filter.Text != "" {
+		query = query.Where(`(
+			to_tsvector('simple', COALESCE(cards.title, '') || ' ' || COALESCE(cards.description, '')) @@ websearch_to_tsquery('simple', ?)
+			OR EXISTS (
+				SELECT 1 FROM card_comments
+				WHERE card_comments.card_id = cards.id
+					AND card_comments.deleted_at IS NULL
+					AND to_tsvector('simple', card_comments.comment) @@ websearch_to_tsquery('simple', ?)
+			)
+		)`, filter.Text, filter.Text)
+	}
+	if len(filter.ListIDs) > 0 {
+		query = query.Where("cards.list_id IN ?", filter.ListIDs)
+	}
+	if len(filter.LabelIDs) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM card_labels WHERE card_labels.card_id = cards.id AND card_labels.label_id IN ?)", filter.LabelIDs)
+	}
+	if len(filter.AssigneeIDs) > 0 {
+		query = query.Where("EXISTS (SELECT 1 FROM card_assignees WHERE card_assignees.card_id = cards.id AND card_assignees.user_id IN ?)", filter.AssigneeIDs)
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("cards.priority IN ?", filter.Priorities)
+	}
+	if filter.DeadlineFrom != nil {
+		query = query.Where("cards.deadline >= ?", *filter.DeadlineFrom)
+	}
+	if filter.DeadlineTo != nil {
+		query = query.Where("cards.deadline <= ?", *filter.DeadlineTo)
+	}
+	if filter.Overdue {
+		query = query.Where("cards.deadline < ?", filter.Now)
+	}
+	if filter.Completed != nil {
+		if *filter.Completed {
+			query = query.Where("cards.completed_at IS NOT NULL")
+		} else {
+			query = query.Where("cards.completed_at IS NULL")
+		}
+	}
+	if !filter.Archived {
+		query = query.Where(notArchivedCard)
+	}
+
+	for _, field := range filter.CustomFields {
+		switch field.Op {
+		case CustomFieldOpUnset:
+			query = query.Where("NOT EXISTS (SELECT 1 FROM card_custom_field_values AS v WHERE v.card_id = cards.id AND v.field_id = ?)", field.FieldID)
+		default:
+			condition, args := customFieldCondition(field)
+			query = query.Where(
+				"EXISTS (SELECT 1 FROM card_custom_field_values AS v WHERE v.card_id = cards.id AND v.field_id = ? AND "+condition+")",
+				append([]interface{}{field.FieldID}, args...)...,
+			)
+		}
+	}
+
+	switch filter.Subtasks {
+	case SubtasksComplete:
+		query = query.Where("EXISTS (SELECT 1 FROM subtasks WHERE subtasks.card_id = cards.id)").
+			Where("NOT EXISTS (SELECT 1 FROM subtasks WHERE subtasks.card_id = cards.id AND NOT subtasks.is_done)")
+	case SubtasksIncomplete:
+		query = query.Where("EXISTS (SELECT 1 FROM subtasks WHERE subtasks.card_id = cards.id AND NOT subtasks.is_done)")
+	case SubtasksNone:
+		query = query.Where("NOT EXISTS (SELECT 1 FROM subtasks WHERE subtasks.card_id = cards.id)")
+	}
+
+	if filter.AfterID != 0 {
+		query = query.Where(
+			fmt.Sprintf("(%s, cards.id) %s (CAST(? AS %s), ?)", sort.expr, comparison, sort.cast),
+			filter.AfterKey, filter.AfterID,
+		)
+	}
+
+	query = query.Order(fmt.Sprintf("%s %s, cards.id %s", sort.expr, direction, direction))
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var hits []CardSearchHit
+	err := query.Scan(&hits).Error
+	return hits, err
+}
+
+// GetCardsByIDs retrieves the cards with the given IDs, preloading their associations,
+// and returns them in the order of the IDs.
+func GetCardsByIDs(ids []uint, cards *[]models.Card) error {
+	if len(ids) == 0 {
+		*cards = []models.Card{}
+		return nil
+	}
+
+	var found []models.Card
+	if err := withCardDetails(database.DB.Where("id IN ?", ids)).Find(&found).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]models.Card, len(found))
+	for _, card := range found {
+		byID[card.ID] = card
+	}
+
+	ordered := make([]models.Card, 0, len(ids))
+	for _, id := range ids {
+		if card, ok := byID[id]; ok {
+			ordered = append(ordered, card)
+		}
+	}
+	*cards = ordered
+	return nil
+}
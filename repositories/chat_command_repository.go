@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateChatCommand creates a new chat slash command.
+func CreateChatCommand(command *models.ChatCommand) error {
+	return database.DB.Omit(clause.Associations).Create(command).Error
+}
+
+// GetChatCommandsByWorkspace retrieves the chat slash commands of a workspace, oldest first.
+func GetChatCommandsByWorkspace(workspaceID uint, commands *[]models.ChatCommand) error {
+	return database.DB.Where("workspace_id = ?", workspaceID).Order("id ASC").Find(commands).Error
+}
+
+// GetChatCommandByID retrieves a chat slash command by its ID.
+func GetChatCommandByID(id uint, command *models.ChatCommand) error {
+	return database.DB.First(command, id).Error
+}
+
+// UpdateChatCommand updates an existing chat slash command.
+func UpdateChatCommand(command *models.ChatCommand) error {
+	return database.DB.Omit(clause.Associations).Save(command).Error
+}
+
+// DeleteChatCommand deletes a chat slash command.
+func DeleteChatCommand(id uint) error {
+	return database.DB.Delete(&models.ChatCommand{}, id).Error
+}
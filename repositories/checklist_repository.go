@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// byPosition orders checklists and subtasks by their position, oldest first among equals.
+func byPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+// CreateChecklist creates a new checklist on a card.
+func CreateChecklist(checklist *models.Checklist) error {
+	return database.DB.Create(checklist).Error
+}
+
+// GetChecklistByID retrieves a checklist by its ID, with its items in order.
+func GetChecklistByID(id uint, checklist *models.Checklist) error {
+	return database.DB.Preload("Items", byPosition).First(checklist, id).Error
+}
+
+// GetChecklistsByCard retrieves the checklists of a card in order, with their items in order.
+func GetChecklistsByCard(cardID uint, checklists *[]models.Checklist) error {
+	return byPosition(database.DB.Where("card_id = ?", cardID)).
+		Preload("Items", byPosition).
+		Find(checklists).Error
+}
+
+// UpdateChecklistTitle renames a checklist.
+func UpdateChecklistTitle(id uint, title string) error {
+	return database.DB.Model(&models.Checklist{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"title": title, "updated_at": time.Now()}).Error
+}
+
+// DeleteChecklist deletes a checklist together with its items.
+func DeleteChecklist(id uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("checklist_id = ?", id).Delete(&models.Subtask{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Checklist{}, id).Error
+	})
+}
+
+// NextChecklistPosition returns the position after the last checklist of a card.
+func NextChecklistPosition(cardID uint) (int, error) {
+	var position int
+	err := database.DB.Model(&models.Checklist{}).
+		Where("card_id = ?", cardID).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Scan(&position).Error
+	return position, err
+}
+
+// ReorderChecklists gives the checklists of a card the positions of their IDs in ids.
+func ReorderChecklists(cardID uint, ids []uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&models.Checklist{}).
+				Where("id = ? AND card_id = ?", id, cardID).
+				Updates(map[string]interface{}{"position": position, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
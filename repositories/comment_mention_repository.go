@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ReplaceCommentMentions replaces all stored mentions of a comment with the given ones.
+func ReplaceCommentMentions(commentID uint, mentions []models.CommentMention) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comment_id = ?", commentID).Delete(&models.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if len(mentions) == 0 {
+			return nil
+		}
+		return tx.Create(&mentions).Error
+	})
+}
+
+// GetMentionedUserIDsByComment retrieves the IDs of the users mentioned in a comment.
+func GetMentionedUserIDsByComment(commentID uint) ([]uint, error) {
+	var ids []uint
+	err := database.DB.
+		Model(&models.CommentMention{}).
+		Where("comment_id = ?", commentID).
+		Distinct().
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
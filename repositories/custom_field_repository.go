@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateCustomField creates a custom field.
+func CreateCustomField(field *models.CustomField) error {
+	return database.DB.Create(field).Error
+}
+
+// GetCustomFieldsByWorkspace retrieves the custom fields of a workspace in order.
+func GetCustomFieldsByWorkspace(workspaceID uint, fields *[]models.CustomField) error {
+	return byPosition(database.DB.Where("workspace_id = ?", workspaceID)).Find(fields).Error
+}
+
+// GetCustomFieldByID retrieves a custom field by its ID.
+func GetCustomFieldByID(id uint, field *models.CustomField) error {
+	return database.DB.First(field, id).Error
+}
+
+// UpdateCustomField writes the given columns of a custom field.
+func UpdateCustomField(field *models.CustomField, columns []string) error {
+	return database.DB.Model(field).Select(append(columns, "updated_at")).Updates(field).Error
+}
+
+// DeleteCustomField deletes a custom field together with its values.
+func DeleteCustomField(id uint) error {
+	return database.DB.Delete(&models.CustomField{}, id).Error
+}
+
+// NextCustomFieldPosition returns the position after the last custom field of a workspace.
+func NextCustomFieldPosition(workspaceID uint) (int, error) {
+	var position int
+	err := database.DB.Model(&models.CustomField{}).
+		Where("workspace_id = ?", workspaceID).
+		Select("COALESCE(MAX(position), -1) + 1").
+		Scan(&position).Error
+	return position, err
+}
+
+// GetCustomFieldValuesByField retrieves every card value of a custom field.
+func GetCustomFieldValuesByField(fieldID uint, values *[]models.CardCustomFieldValue) error {
+	return database.DB.Where("field_id = ?", fieldID).Find(values).Error
+}
+
+// SetCardCustomFieldValue stores the value of a custom field on a card, replacing any previous value.
+func SetCardCustomFieldValue(value *models.CardCustomFieldValue) error {
+	return database.DB.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "card_id"}, {Name: "field_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(value).Error
+}
+
+// UpdateCardCustomFieldValue rewrites a stored custom field value.
+func UpdateCardCustomFieldValue(id uint, value models.JSONValue) error {
+	return database.DB.Model(&models.CardCustomFieldValue{}).Where("id = ?", id).Update("value", value).Error
+}
+
+// DeleteCardCustomFieldValue removes the value of a custom field from a card.
+func DeleteCardCustomFieldValue(cardID, fieldID uint) error {
+	return database.DB.Where("card_id = ? AND field_id = ?", cardID, fieldID).Delete(&models.CardCustomFieldValue{}).Error
+}
+
+// DeleteCardCustomFieldValueByID removes a stored custom field value.
+func DeleteCardCustomFieldValueByID(id uint) error {
+	return database.DB.Delete(&models.CardCustomFieldValue{}, id).Error
+}
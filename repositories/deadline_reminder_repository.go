@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateDeadlineReminderWithNotification records a deadline reminder and creates its notification
+// in a single transaction. It returns false without creating the notification if the reminder
+// has already been recorded.
+func CreateDeadlineReminderWithNotification(reminder *models.DeadlineReminder, notification *models.Notification) (bool, error) {
+	created := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		created = true
+		return tx.Create(notification).Error
+	})
+	return created, err
+}
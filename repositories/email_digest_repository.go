@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+)
+
+// GetEmailDigestSetting retrieves a user's email digest setting.
+func GetEmailDigestSetting(userID uint, setting *models.EmailDigestSetting) error {
+	return database.DB.Where("user_id = ?", userID).First(setting).Error
+}
+
+// SaveEmailDigestSetting creates or updates a user's email digest setting.
+func SaveEmailDigestSetting(setting *models.EmailDigestSetting) error {
+	return database.DB.Save(setting).Error
+}
+
+// GetActiveEmailDigestSettings retrieves every digest setting with a daily or weekly frequency,
+// preloading the user.
+func GetActiveEmailDigestSettings(settings *[]models.EmailDigestSetting) error {
+	return database.DB.
+		Where("frequency IN ?", []string{models.DigestFrequencyDaily, models.DigestFrequencyWeekly}).
+		Preload("User").
+		Find(settings).Error
+}
+
+// MarkEmailDigestSent records when a user's digest was last sent.
+func MarkEmailDigestSent(userID uint, sentAt time.Time) error {
+	return database.DB.
+		Model(&models.EmailDigestSetting{}).
+		Where("user_id = ?", userID).
+		Update("last_sent_at", sentAt).Error
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// GetInboundAddressByListID retrieves the inbound email address of a board list.
+func GetInboundAddressByListID(listID uint, address *models.ListInboundAddress) error {
+	return database.DB.Where("list_id = ?", listID).First(address).Error
+}
+
+// GetInboundAddressesByTokens retrieves the inbound email addresses with any of the given tokens,
+// together with their lists.
+func GetInboundAddressesByTokens(tokens []string, addresses *[]models.ListInboundAddress) error {
+	return database.DB.Preload("List").Where("token IN ?", tokens).Find(addresses).Error
+}
+
+// ReplaceInboundAddress deletes any existing inbound address of the same list and stores the new one.
+func ReplaceInboundAddress(address *models.ListInboundAddress) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", address.ListID).Delete(&models.ListInboundAddress{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(address).Error
+	})
+}
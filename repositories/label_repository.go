@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+)
+
+// CreateLabel creates a new label in a workspace's label catalog.
+func CreateLabel(label *models.Label) error {
+	return database.DB.Create(label).Error
+}
+
+// GetLabelsByWorkspace retrieves the label catalog of a workspace, ordered by name.
+func GetLabelsByWorkspace(workspaceID uint, labels *[]models.Label) error {
+	return database.DB.
+		Where("workspace_id = ?", workspaceID).
+		Order("LOWER(name) ASC").
+		Find(labels).Error
+}
+
+// GetLabelByID retrieves a catalog label by its ID.
+func GetLabelByID(id uint, label *models.Label) error {
+	return database.DB.First(label, id).Error
+}
+
+// GetLabelByName retrieves a workspace's catalog label by name, ignoring case.
+func GetLabelByName(workspaceID uint, name string, label *models.Label) error {
+	return database.DB.
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?)", workspaceID, name).
+		First(label).Error
+}
+
+// IsLabelNameTaken checks whether another label in the workspace already uses the name, ignoring case.
+func IsLabelNameTaken(workspaceID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := database.DB.
+		Model(&models.Label{}).
+		Where("workspace_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", workspaceID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateLabel updates an existing catalog label.
+func UpdateLabel(label *models.Label) error {
+	return database.DB.Save(label).Error
+}
+
+// DeleteLabel deletes a catalog label by its ID, removing it from every card.
+func DeleteLabel(id uint) error {
+	return database.DB.Delete(&models.Label{}, id).Error
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateNotification creates a new notification.
+func CreateNotification(notification *models.Notification) error {
+	return database.DB.Create(notification).Error
+}
+
+// GetNotificationsByUser retrieves a page of a user's notifications, newest first,
+// and returns the total number of matching notifications.
+func GetNotificationsByUser(userID uint, unreadOnly bool, limit, offset int, notifications *[]models.Notification) (int64, error) {
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(notifications).Error
+	return total, err
+}
+
+// CountUnreadNotifications returns the number of unread notifications of a user.
+func CountUnreadNotifications(userID uint) (int64, error) {
+	var count int64
+	err := database.DB.
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetNotificationByID retrieves a notification by its ID.
+func GetNotificationByID(id uint, notification *models.Notification) error {
+	return database.DB.First(notification, id).Error
+}
+
+// MarkNotificationRead marks a single notification of a user as read.
+func MarkNotificationRead(id, userID uint, readAt time.Time) error {
+	return database.DB.
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", readAt).Error
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read.
+func MarkAllNotificationsRead(userID uint, readAt time.Time) (int64, error) {
+	result := database.DB.
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+	return result.RowsAffected, result.Error
+}
+
+// GetNotificationPreferences retrieves the stored notification preferences of a user.
+func GetNotificationPreferences(userID uint, preferences *[]models.NotificationPreference) error {
+	return database.DB.Where("user_id = ?", userID).Find(preferences).Error
+}
+
+// IsNotificationEnabled reports whether a user wants notifications of the given type.
+func IsNotificationEnabled(userID uint, notificationType string) (bool, error) {
+	var preferences []models.NotificationPreference
+	if err := database.DB.
+		Where("user_id = ? AND type = ?", userID, notificationType).
+		Limit(1).
+		Find(&preferences).Error; err != nil {
+		return false, err
+	}
+	if len(preferences) == 0 {
+		return true, nil
+	}
+	return preferences[0].Enabled, nil
+}
+
+// SaveNotificationPreference creates or updates a notification preference.
+func SaveNotificationPreference(preference *models.NotificationPreference) error {
+	return database.DB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+		}).
+		Create(preference).Error
+}
@@ -3,6 +3,9 @@ package repositories
 import (
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateSubtask creates a new subtask for a card.
@@ -10,9 +13,9 @@ func CreateSubtask(subtask *models.Subtask) error {
 	return database.DB.Create(subtask).Error
 }
 
-// GetSubtasksByCard retrieves subtasks for a given card.
+// GetSubtasksByCard retrieves subtasks for a given card, in order.
 func GetSubtasksByCard(cardID uint, subtasks *[]models.Subtask) error {
-	return database.DB.Where("card_id = ?", cardID).Find(subtasks).Error
+	return byPosition(database.DB.Where("card_id = ?", cardID)).Find(subtasks).Error
 }
 
 // GetSubtaskByID retrieves a subtask by its ID.
@@ -20,12 +23,90 @@ func GetSubtaskByID(id uint, subtask *models.Subtask) error {
 	return database.DB.First(subtask, id).Error
 }
 
-// UpdateSubtask updates an existing subtask.
-func UpdateSubtask(subtask *models.Subtask) error {
-	return database.DB.Save(subtask).Error
+// UpdateSubtask writes the given columns of a subtask if it is still at the version it was
+// loaded with, incrementing the version. It returns false if someone else has updated it since.
+func UpdateSubtask(subtask *models.Subtask, columns []string) (bool, error) {
+	expected := subtask.Version
+	subtask.Version++
+	ok, err := updateVersioned(subtask, expected, append(columns, "version"))
+	if !ok {
+		subtask.Version = expected
+	}
+	return ok, err
 }
 
 // DeleteSubtask deletes a subtask by its ID.
 func DeleteSubtask(id uint) error {
 	return database.DB.Delete(&models.Subtask{}, id).Error
 }
+
+// CompleteSubtasksByCard marks every open subtask of a card as done.
+func CompleteSubtasksByCard(cardID uint) error {
+	return database.DB.Model(&models.Subtask{}).
+		Where("card_id = ? AND is_done = ?", cardID, false).
+		Updates(map[string]interface{}{"is_done": true, "version": bumpVersion}).Error
+}
+
+// CountOpenSubtasks returns how many subtasks of a card are not done.
+func CountOpenSubtasks(cardID uint) (int64, error) {
+	var count int64
+	err := database.DB.Model(&models.Subtask{}).Where("card_id = ? AND is_done = ?", cardID, false).Count(&count).Error
+	return count, err
+}
+
+// NextSubtaskPosition returns the position after the last subtask of a checklist or, with a nil
+// checklistID, after the last subtask of the card that is not in a checklist.
+func NextSubtaskPosition(cardID uint, checklistID *uint) (int, error) {
+	query := database.DB.Model(&models.Subtask{}).Where("card_id = ?", cardID)
+	if checklistID == nil {
+		query = query.Where("checklist_id IS NULL")
+	} else {
+		query = query.Where("checklist_id = ?", *checklistID)
+	}
+
+	var position int
+	err := query.Select("COALESCE(MAX(position), -1) + 1").Scan(&position).Error
+	return position, err
+}
+
+// ReorderSubtasks gives the subtasks of a checklist the positions of their IDs in ids.
+func ReorderSubtasks(checklistID uint, ids []uint) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(&models.Subtask{}).
+				Where("id = ? AND checklist_id = ?", id, checklistID).
+				Updates(map[string]interface{}{"position": position, "version": bumpVersion}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConvertSubtaskToCard creates card, and the link relating it to the subtask's card, and
+// deletes the subtask, in one transaction. It returns an error wrapping ErrWIPLimitReached if
+// the card's list is at its hard WIP limit.
+func ConvertSubtaskToCard(subtask *models.Subtask, card *models.Card, link *models.CardLink) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := reserveListCapacity(tx, card.ListID, 1); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(card).Error; err != nil {
+			return err
+		}
+		for i := range card.Assignees {
+			card.Assignees[i].CardID = card.ID
+		}
+		if len(card.Assignees) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&card.Assignees).Error; err != nil {
+				return err
+			}
+		}
+
+		link.LinkedCardID = card.ID
+		if err := tx.Omit(clause.Associations).Create(link).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Subtask{}, subtask.ID).Error
+	})
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TimeTotal is the tracked time of one card or one user.
+type TimeTotal struct {
+	ID      uint
+	Seconds int64
+}
+
+// trackedSecondsExpr sums finished entries by their duration and running timers up to now.
+const trackedSecondsExpr = "COALESCE(SUM(CASE WHEN time_entries.ended_at IS NULL " +
+	"THEN EXTRACT(EPOCH FROM (?::timestamptz - time_entries.started_at))::bigint " +
+	"ELSE time_entries.duration_seconds END), 0)"
+
+// stopRunningTimers ends every running timer of a user at the given time.
+func stopRunningTimers(tx *gorm.DB, userID uint, at time.Time) error {
+	return tx.Model(&models.TimeEntry{}).
+		Where("user_id = ? AND ended_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"ended_at":         at,
+			"duration_seconds": gorm.Expr("GREATEST(EXTRACT(EPOCH FROM (?::timestamptz - started_at))::bigint, 0)", at),
+		}).Error
+}
+
+// StartTimer stops any timer the user has running and starts the given one.
+func StartTimer(entry *models.TimeEntry) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := stopRunningTimers(tx, entry.UserID, entry.StartedAt); err != nil {
+			return err
+		}
+		return tx.Omit(clause.Associations).Create(entry).Error
+	})
+}
+
+// GetRunningTimeEntry retrieves the timer a user has running on a card.
+func GetRunningTimeEntry(cardID, userID uint, entry *models.TimeEntry) error {
+	return database.DB.
+		Where("card_id = ? AND user_id = ? AND ended_at IS NULL", cardID, userID).
+		First(entry).Error
+}
+
+// CreateTimeEntry creates a manually logged time entry.
+func CreateTimeEntry(entry *models.TimeEntry) error {
+	return database.DB.Omit(clause.Associations).Create(entry).Error
+}
+
+// UpdateTimeEntry updates an existing time entry.
+func UpdateTimeEntry(entry *models.TimeEntry) error {
+	return database.DB.Omit(clause.Associations).Save(entry).Error
+}
+
+// GetTimeEntriesByCard retrieves the time entries of a card, newest first, preloading their user.
+func GetTimeEntriesByCard(cardID uint, entries *[]models.TimeEntry) error {
+	return database.DB.
+		Where("card_id = ?", cardID).
+		Order("started_at DESC").
+		Preload("User").
+		Find(entries).Error
+}
+
+// GetTimeEntryByID retrieves a time entry by its ID.
+func GetTimeEntryByID(id uint, entry *models.TimeEntry) error {
+	return database.DB.First(entry, id).Error
+}
+
+// DeleteTimeEntry deletes a time entry by its ID.
+func DeleteTimeEntry(id uint) error {
+	return database.DB.Delete(&models.TimeEntry{}, id).Error
+}
+
+// GetWorkspaceTimeTotals sums the time tracked in a workspace per card and per user, counting
+// running timers up to now. Entries are included when they started in [from, to); nil bounds
+// are open.
+func GetWorkspaceTimeTotals(workspaceID uint, from, to *time.Time, now time.Time) ([]TimeTotal, []TimeTotal, error) {
+	query := func() *gorm.DB {
+		q := database.DB.Model(&models.TimeEntry{}).
+			Joins("JOIN cards ON cards.id = time_entries.card_id").
+			Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+			Where("board_lists.workspace_id = ? AND cards.deleted_at IS NULL", workspaceID)
+		if from != nil {
+			q = q.Where("time_entries.started_at >= ?", *from)
+		}
+		if to != nil {
+			q = q.Where("time_entries.started_at < ?", *to)
+		}
+		return q
+	}
+
+	var byCard, byUser []TimeTotal
+	if err := query().
+		Select("time_entries.card_id AS id, "+trackedSecondsExpr+" AS seconds", now).
+		Group("time_entries.card_id").
+		Order("seconds DESC").
+		Scan(&byCard).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := query().
+		Select("time_entries.user_id AS id, "+trackedSecondsExpr+" AS seconds", now).
+		Group("time_entries.user_id").
+		Order("seconds DESC").
+		Scan(&byUser).Error; err != nil {
+		return nil, nil, err
+	}
+	return byCard, byUser, nil
+}
@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrParentDeleted is returned when restoring an item whose list or workspace is still in the trash.
+var ErrParentDeleted = errors.New("parent is in the trash")
+
+// TrashedCard is a card in the trash with the title of its list.
+type TrashedCard struct {
+	models.Card
+	ListTitle string
+}
+
+// TrashedList is a list in the trash with the number of cards deleted with it.
+type TrashedList struct {
+	models.BoardList
+	CardCount int64
+}
+
+// GetDeletedWorkspacesByOwner retrieves the workspaces a user owns that are in the trash,
+// most recently deleted first.
+func GetDeletedWorkspacesByOwner(ownerID uint, workspaces *[]models.Workspace) error {
+	return database.DB.Unscoped().
+		Where("owner_id = ? AND deleted_at IS NOT NULL", ownerID).
+		Order("deleted_at DESC").
+		Find(workspaces).Error
+}
+
+// GetDeletedWorkspaceByID retrieves a workspace in the trash by its ID.
+func GetDeletedWorkspaceByID(id uint, workspace *models.Workspace) error {
+	return database.DB.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(workspace, id).Error
+}
+
+// GetTrashedListsByWorkspace retrieves the lists of a workspace that were deleted on their own,
+// most recently deleted first, with the number of cards deleted with each.
+func GetTrashedListsByWorkspace(workspaceID uint, lists *[]TrashedList) error {
+	return database.DB.Unscoped().
+		Model(&models.BoardList{}).
+		Select("board_lists.*, (SELECT COUNT(*) FROM cards WHERE cards.list_id = board_lists.id AND cards.deleted_at = board_lists.deleted_at) AS card_count").
+		Where("board_lists.workspace_id = ? AND board_lists.deleted_at IS NOT NULL", workspaceID).
+		Order("board_lists.deleted_at DESC").
+		Scan(lists).Error
+}
+
+// GetTrashedCardsByWorkspace retrieves the cards of a workspace that were deleted on their own,
+// rather than together with their list, most recently deleted first.
+func GetTrashedCardsByWorkspace(workspaceID uint, cards *[]TrashedCard) error {
+	return database.DB.Unscoped().
+		Model(&models.Card{}).
+		Select("cards.*, board_lists.title AS list_title").
+		Joins("JOIN board_lists ON board_lists.id = cards.list_id").
+		Where("board_lists.workspace_id = ? AND cards.deleted_at IS NOT NULL", workspaceID).
+		Where("board_lists.deleted_at IS NULL OR board_lists.deleted_at <> cards.deleted_at").
+		Order("cards.deleted_at DESC").
+		Scan(cards).Error
+}
+
+// GetDeletedBoardListByID retrieves a board list in the trash by its ID.
+func GetDeletedBoardListByID(id uint, list *models.BoardList) error {
+	return database.DB.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(list, id).Error
+}
+
+// GetDeletedCardWithListByID retrieves a card in the trash by its ID, with its list preloaded
+// even if the list is in the trash too.
+func GetDeletedCardWithListByID(id uint, card *models.Card) error {
+	return database.DB.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Preload("List", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(card, id).Error
+}
+
+// RestoreWorkspace brings a workspace back from the trash together with the lists and cards
+// deleted with it.
+func RestoreWorkspace(workspace *models.Workspace) error {
+	deletedAt := workspace.DeletedAt.Time
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		listQuery := tx.Unscoped().Model(&models.BoardList{}).Select("id").
+			Where("workspace_id = ? AND deleted_at = ?", workspace.ID, deletedAt)
+		if err := tx.Unscoped().Model(&models.Card{}).
+			Where("list_id IN (?) AND deleted_at = ?", listQuery, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.BoardList{}).
+			Where("workspace_id = ? AND deleted_at = ?", workspace.ID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// RestoreBoardList brings a list back from the trash together with the cards deleted with it.
+// It returns ErrParentDeleted if the list's workspace is in the trash.
+func RestoreBoardList(list *models.BoardList) error {
+	deletedAt := list.DeletedAt.Time
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Workspace{}).Where("id = ?", list.WorkspaceID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrParentDeleted
+		}
+
+		if err := tx.Unscoped().Model(&models.Card{}).
+			Where("list_id = ? AND deleted_at = ?", list.ID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.BoardList{}).
+			Where("id = ?", list.ID).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// RestoreCard brings a card back from the trash. It returns ErrParentDeleted if the card's
+// list is in the trash, and an error wrapping ErrWIPLimitReached if the list is at its hard
+// WIP limit.
+func RestoreCard(card *models.Card) error {
+	if card.List.DeletedAt.Valid {
+		return ErrParentDeleted
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if card.ArchivedAt == nil {
+			if err := reserveListCapacity(tx, card.ListID, 1); err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&models.Card{}).
+			Where("id = ?", card.ID).
+			Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeTrash permanently deletes the workspaces, lists and cards that were moved to the trash
+// before the cutoff. Their remaining children are removed by the ON DELETE CASCADE constraints.
+// It returns the number of rows deleted.
+func PurgeTrash(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Card{}, &models.BoardList{}, &models.Workspace{}} {
+			result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(model)
+			if result.Error != nil {
+				return result.Error
+			}
+			purged += result.RowsAffected
+		}
+		return nil
+	})
+	return purged, err
+}
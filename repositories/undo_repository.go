@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateUndoAction records an undoable operation.
+func CreateUndoAction(action *models.UndoAction) error {
+	return database.DB.Omit(clause.Associations).Create(action).Error
+}
+
+// GetUndoActionByToken retrieves an undoable operation by its token.
+func GetUndoActionByToken(token string, action *models.UndoAction) error {
+	return database.DB.Where("token = ?", token).First(action).Error
+}
+
+// MarkUndoActionUndone marks an operation as undone. It returns false if it had already been
+// undone, so that concurrent requests cannot undo the same operation twice.
+func MarkUndoActionUndone(id uint, undoneAt time.Time) (bool, error) {
+	result := database.DB.Model(&models.UndoAction{}).
+		Where("id = ? AND undone_at IS NULL", id).
+		Update("undone_at", undoneAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseUndoActionUndone clears the mark set by MarkUndoActionUndone, so that an operation
+// whose reversal failed can be undone again.
+func ReleaseUndoActionUndone(id uint) error {
+	return database.DB.Model(&models.UndoAction{}).
+		Where("id = ?", id).
+		Update("undone_at", nil).Error
+}
+
+// DeleteExpiredUndoActions deletes the operations of a user that can no longer be undone.
+func DeleteExpiredUndoActions(userID uint, now time.Time) error {
+	return database.DB.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.UndoAction{}).Error
+}
+
+// GetRowByKey loads a row by its primary key columns, including soft-deleted rows.
+func GetRowByKey(model interface{}, key map[string]interface{}) error {
+	return database.DB.Unscoped().Where(key).First(model).Error
+}
+
+// SaveRow writes every column of a row, including soft-deleted rows, without its associations.
+func SaveRow(model interface{}) error {
+	return database.DB.Unscoped().Omit(clause.Associations).Save(model).Error
+}
+
+// CreateRow inserts a row, keeping its primary key, without its associations.
+func CreateRow(model interface{}) error {
+	return database.DB.Omit(clause.Associations).Create(model).Error
+}
+
+// DeleteRowByKey permanently deletes a row by its primary key columns.
+func DeleteRowByKey(model interface{}, key map[string]interface{}) error {
+	return database.DB.Unscoped().Where(key).Delete(model).Error
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"kelarin-backend/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bumpVersion is the update expression that increments a row's version.
+var bumpVersion = gorm.Expr("version + 1")
+
+// updateVersioned writes the given columns of a model, identified by its primary key, only if
+// the row is still at the expected version. It returns false if no row matched.
+func updateVersioned(model interface{}, expected uint, columns []string) (bool, error) {
+	return updateVersionedTx(database.DB, model, expected, columns)
+}
+
+// updateVersionedTx is updateVersioned within a transaction.
+func updateVersionedTx(tx *gorm.DB, model interface{}, expected uint, columns []string) (bool, error) {
+	result := tx.Model(model).
+		Where("version = ?", expected).
+		Select(columns).
+		Omit(clause.Associations).
+		Updates(model)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"time"
+
+	"kelarin-backend/database"
+	"kelarin-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateWebhook creates a new webhook subscription.
+func CreateWebhook(webhook *models.Webhook) error {
+	return database.DB.Omit(clause.Associations).Create(webhook).Error
+}
+
+// GetWebhooksByWorkspace retrieves the webhooks of a workspace, oldest first.
+func GetWebhooksByWorkspace(workspaceID uint, webhooks *[]models.Webhook) error {
+	return database.DB.Where("workspace_id = ?", workspaceID).Order("id ASC").Find(webhooks).Error
+}
+
+// GetEnabledWebhooksByWorkspace retrieves the enabled webhooks of a workspace.
+func GetEnabledWebhooksByWorkspace(workspaceID uint, webhooks *[]models.Webhook) error {
+	return database.DB.Where("workspace_id = ? AND enabled = ?", workspaceID, true).Order("id ASC").Find(webhooks).Error
+}
+
+// GetWebhookByID retrieves a webhook by its ID.
+func GetWebhookByID(id uint, webhook *models.Webhook) error {
+	return database.DB.First(webhook, id).Error
+}
+
+// UpdateWebhook updates an existing webhook.
+func UpdateWebhook(webhook *models.Webhook) error {
+	return database.DB.Omit(clause.Associations).Save(webhook).Error
+}
+
+// DeleteWebhook deletes a webhook together with its deliveries.
+func DeleteWebhook(id uint) error {
+	return database.DB.Delete(&models.Webhook{}, id).Error
+}
+
+// CreateWebhookDeliveries queues new webhook deliveries.
+func CreateWebhookDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return database.DB.Omit(clause.Associations).Create(&deliveries).Error
+}
+
+// CreateWebhookDelivery records a single webhook delivery.
+func CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return database.DB.Omit(clause.Associations).Create(delivery).Error
+}
+
+// ClaimDueWebhookDeliveries retrieves up to limit pending deliveries whose next attempt is due,
+// oldest first, preloading their webhook. Their next attempt is pushed to claimUntil, so that
+// other workers skip them while they are being sent; rows locked by another worker are skipped.
+func ClaimDueWebhookDeliveries(now, claimUntil time.Time, limit int, deliveries *[]models.WebhookDelivery) error {
+	var ids []uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.WebhookDelivery{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", claimUntil).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return database.DB.Where("id IN ?", ids).Order("id ASC").Preload("Webhook").Find(deliveries).Error
+}
+
+// GetWebhookDeliveries retrieves the latest deliveries of a webhook, newest first.
+func GetWebhookDeliveries(webhookID uint, limit int, deliveries *[]models.WebhookDelivery) error {
+	return database.DB.Where("webhook_id = ?", webhookID).Order("created_at DESC, id DESC").Limit(limit).Find(deliveries).Error
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt.
+func UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return database.DB.Omit(clause.Associations).Save(delivery).Error
+}
+
+// DeleteWebhookDeliveriesBefore deletes finished deliveries created before the cutoff and
+// returns how many were deleted.
+func DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error) {
+	result := database.DB.Where("status <> ? AND created_at < ?", models.WebhookDeliveryPending, cutoff).Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -1,8 +1,13 @@
 package repositories
 
 import (
+	"strings"
+	"time"
+
 	"kelarin-backend/database"
 	"kelarin-backend/models"
+
+	"gorm.io/gorm"
 )
 
 // CreateWorkspace creates a new workspace in the database.
@@ -91,7 +96,72 @@ func IsUserAlreadyCollaborator(workspaceID uint, userID uint) (bool, error) {
 	return count > 0, err
 }
 
-// DeleteWorkspace deletes a workspace by its ID.
+// UpdateWorkspace writes the given columns of a workspace if it is still at the version it was
+// loaded with, incrementing the version. It returns false if someone else has updated it since.
+func UpdateWorkspace(workspace *models.Workspace, columns []string) (bool, error) {
+	expected := workspace.Version
+	workspace.Version++
+	workspace.UpdatedAt = time.Now()
+	ok, err := updateVersioned(workspace, expected, append(columns, "version", "updated_at"))
+	if !ok {
+		workspace.Version = expected
+	}
+	return ok, err
+}
+
+// GetWorkspaceOwnerID returns the ID of the owner of a workspace.
+func GetWorkspaceOwnerID(workspaceID uint) (uint, error) {
+	var workspace models.Workspace
+	if err := database.DB.Select("owner_id").First(&workspace, workspaceID).Error; err != nil {
+		return 0, err
+	}
+	return workspace.OwnerID, nil
+}
+
+// DeleteWorkspace moves a workspace, its lists and their cards to the trash. They share the
+// same deletion time, so restoring the workspace brings back exactly what was deleted with it.
 func DeleteWorkspace(id string) error {
-	return database.DB.Delete(&models.Workspace{}, id).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		listQuery := tx.Model(&models.BoardList{}).Select("id").Where("workspace_id = ?", id)
+		if err := tx.Model(&models.Card{}).Where("list_id IN (?)", listQuery).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.BoardList{}).Where("workspace_id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Workspace{}).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+}
+
+// GetWorkspaceMembers retrieves every user with access to a workspace: the owner and all collaborators.
+func GetWorkspaceMembers(workspaceID uint, users *[]models.User) error {
+	ownerQuery := database.DB.Table("workspaces").Select("owner_id").Where("id = ?", workspaceID)
+	collabQuery := database.DB.Table("workspace_users").Select("user_id").Where("workspace_id = ?", workspaceID)
+
+	return database.DB.
+		Where("id IN (?) OR id IN (?)", ownerQuery, collabQuery).
+		Order("full_name ASC").
+		Find(users).Error
+}
+
+// likeEscaper escapes the LIKE wildcards in user input so it only matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchWorkspaceMembers retrieves up to limit workspace members whose name or email contains the query.
+func SearchWorkspaceMembers(workspaceID uint, query string, limit int, users *[]models.User) error {
+	ownerQuery := database.DB.Table("workspaces").Select("owner_id").Where("id = ?", workspaceID)
+	collabQuery := database.DB.Table("workspace_users").Select("user_id").Where("workspace_id = ?", workspaceID)
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	return database.DB.
+		Where("id IN (?) OR id IN (?)", ownerQuery, collabQuery).
+		Where("full_name ILIKE ? OR email ILIKE ?", pattern, pattern).
+		Order("full_name ASC").
+		Limit(limit).
+		Find(users).Error
 }
@@ -25,10 +25,42 @@ func SetupRoutes(app *fiber.App) {
 	workspace.Post("/:id/share", controllers.ShareWorkspace)          // Share workspace
 	workspace.Get("/all", controllers.GetAllWorkspaces)               // Get all workspaces
 	workspace.Get("/accessible", controllers.GetAccessibleWorkspaces) // Get accessible workspaces
+	workspace.Get("/trash", controllers.GetDeletedWorkspaces)         // Get own workspaces in the trash
+	workspace.Post("/:id/restore", controllers.RestoreWorkspace)      // Restore workspace from the trash
+	workspace.Get("/:id/members", controllers.GetMentionCandidates)   // Autocomplete members for @mentions
 	workspace.Get("/:id", controllers.GetWorkspace)                   // Get workspace by ID
 	workspace.Put("/:id", controllers.UpdateWorkspace)                // Update workspace
+	workspace.Patch("/:id", controllers.UpdateWorkspace)              // Partially update workspace
 	workspace.Delete("/:id", controllers.DeleteWorkspace)             // Delete workspace
 
+	// Current user routes
+	me := api.Group("/me", middleware.AuthMiddleware)
+	me.Get("/cards", controllers.GetMyCards)                        // Cards assigned to the user across workspaces
+	me.Get("/calendar", controllers.GetMyCalendar)                  // Assigned cards by deadline range
+	me.Get("/calendar/feed", controllers.GetMyCalendarFeed)         // Personal iCalendar subscription URL
+	me.Post("/calendar/feed", controllers.RegenerateMyCalendarFeed) // Regenerate the personal feed token
+
+	// Public iCalendar feeds, authenticated by the secret token in the URL
+	api.Get("/calendar/:token.ics", controllers.ServeCalendarFeed)
+
+	// Chat slash commands, authenticated by the chat provider's request signature
+	api.Post("/integrations/chat/commands/:id", controllers.HandleChatCommand)
+
+	// Inbound email, authenticated by the shared secret configured with the inbound-mail provider
+	api.Post("/inbound/email", controllers.ReceiveInboundEmail)
+	api.Post("/inbound/email/raw", controllers.ReceiveRawInboundEmail)
+
+	// Notification routes
+	notifications := api.Group("/notifications", middleware.AuthMiddleware)
+	notifications.Get("/", controllers.GetNotifications)                         // List notifications with unread count
+	notifications.Put("/read-all", controllers.MarkAllNotificationsRead)         // Mark all notifications as read
+	notifications.Get("/preferences", controllers.GetNotificationPreferences)    // Get notification preferences
+	notifications.Put("/preferences", controllers.UpdateNotificationPreferences) // Update notification preferences
+	notifications.Get("/digest", controllers.GetEmailDigestSetting)              // Get email digest setting
+	notifications.Put("/digest", controllers.UpdateEmailDigestSetting)           // Opt in or out of the email digest
+	notifications.Get("/digest/preview", controllers.PreviewEmailDigest)         // Preview the email digest
+	notifications.Put("/:id/read", controllers.MarkNotificationRead)             // Mark a notification as read
+
 	// Kanban Board routes
 	kanban := api.Group("/kanban", middleware.AuthMiddleware)
 
@@ -36,14 +68,49 @@ func SetupRoutes(app *fiber.App) {
 	kanban.Post("/workspace/:workspace_id/lists", controllers.CreateBoardList)
 	kanban.Get("/workspace/:workspace_id/lists", controllers.GetBoardLists)
 	kanban.Put("/lists/:id", controllers.UpdateBoardList)
+	kanban.Patch("/lists/:id", controllers.UpdateBoardList)
 	kanban.Delete("/lists/:id", controllers.DeleteBoardList)
+	kanban.Post("/lists/:id/complete", controllers.CompleteBoardList)
+	kanban.Post("/lists/:id/reopen", controllers.ReopenBoardList)
+	kanban.Post("/lists/:id/archive", controllers.ArchiveBoardList)
+	kanban.Post("/lists/:id/unarchive", controllers.UnarchiveBoardList)
+	kanban.Get("/lists/:id/inbound-address", controllers.GetListInboundAddress)
+	kanban.Post("/lists/:id/inbound-address", controllers.RegenerateListInboundAddress)
+	kanban.Get("/workspace/:workspace_id/archive", controllers.GetArchivedItems)
+	kanban.Get("/workspace/:workspace_id/trash", controllers.GetWorkspaceTrash)
+	kanban.Post("/lists/:id/restore", controllers.RestoreBoardList)
 
 	// Card routes:
 	kanban.Post("/lists/:list_id/cards", controllers.CreateCard)
 	kanban.Get("/lists/:list_id/cards", controllers.GetCards)
 	kanban.Get("/cards/:id", controllers.GetCard)
 	kanban.Put("/cards/:id", controllers.UpdateCard)
+	kanban.Patch("/cards/:id", controllers.UpdateCard)
 	kanban.Delete("/cards/:id", controllers.DeleteCard)
+	kanban.Post("/cards/:id/complete", controllers.CompleteCard)
+	kanban.Post("/cards/:id/reopen", controllers.ReopenCard)
+	kanban.Post("/cards/:id/archive", controllers.ArchiveCard)
+	kanban.Post("/cards/:id/unarchive", controllers.UnarchiveCard)
+	kanban.Post("/cards/:id/restore", controllers.RestoreCard)
+	kanban.Get("/workspace/:workspace_id/cards/overdue", controllers.GetOverdueCards)
+	kanban.Get("/workspace/:workspace_id/cards/search", controllers.SearchCards)
+	kanban.Post("/cards/bulk", controllers.BulkUpdateCards)
+
+	// Calendar routes:
+	kanban.Get("/workspace/:workspace_id/calendar", controllers.GetWorkspaceCalendar)
+	kanban.Get("/workspace/:workspace_id/calendar/feed", controllers.GetWorkspaceCalendarFeed)
+	kanban.Post("/workspace/:workspace_id/calendar/feed", controllers.RegenerateWorkspaceCalendarFeed)
+
+	// Time tracking routes:
+	kanban.Post("/cards/:card_id/timer/start", controllers.StartTimer)
+	kanban.Post("/cards/:card_id/timer/stop", controllers.StopTimer)
+	kanban.Post("/cards/:card_id/time-entries", controllers.CreateTimeEntry)
+	kanban.Get("/cards/:card_id/time-entries", controllers.GetTimeEntries)
+	kanban.Delete("/time-entries/:id", controllers.DeleteTimeEntry)
+	kanban.Get("/workspace/:workspace_id/time-tracking", controllers.GetWorkspaceTimeTracking)
+
+	// Analytics routes:
+	kanban.Get("/workspace/:workspace_id/analytics", controllers.GetWorkspaceAnalytics)
 
 	// Card Assignee routes:
 	kanban.Post("/cards/:card_id/assignees", controllers.CreateAssignee)
@@ -51,6 +118,12 @@ func SetupRoutes(app *fiber.App) {
 	kanban.Get("/cards/:card_id/assignees/:user_id", controllers.GetAssignee)
 	kanban.Delete("/cards/:card_id/assignees/:user_id", controllers.DeleteAssignee)
 
+	// Label catalog routes:
+	kanban.Post("/workspace/:workspace_id/labels", controllers.CreateLabel)
+	kanban.Get("/workspace/:workspace_id/labels", controllers.GetWorkspaceLabels)
+	kanban.Put("/workspace/:workspace_id/labels/:id", controllers.UpdateLabel)
+	kanban.Delete("/workspace/:workspace_id/labels/:id", controllers.DeleteLabel)
+
 	// Card Label routes:
 	kanban.Post("/cards/:card_id/label", controllers.CreateCardLabel)
 	kanban.Get("/cards/:card_id/labels", controllers.GetLabels)
@@ -61,21 +134,71 @@ func SetupRoutes(app *fiber.App) {
 	// Card Attachment routes:
 	kanban.Post("/cards/:card_id/attachment", controllers.CreateCardAttachment)
 	kanban.Get("/cards/:card_id/attachments", controllers.GetAttachments)
+	kanban.Get("/cards/:card_id/inbound-attachments/:name", controllers.DownloadInboundAttachment)
 	kanban.Get("/cards/attachment/:id", controllers.GetCardAttachment)
 	kanban.Put("/cards/attachment/:id", controllers.UpdateCardAttachment)
 	kanban.Delete("/cards/attachment/:id", controllers.DeleteCardAttachment)
 
+	// Card Link routes:
+	kanban.Post("/cards/:card_id/link", controllers.CreateCardLink)
+	kanban.Get("/cards/:card_id/links", controllers.GetCardLinks)
+	kanban.Delete("/cards/link/:id", controllers.DeleteCardLink)
+
 	// Card Comment routes:
 	kanban.Post("/cards/:card_id/comment", controllers.CreateCardComment)
 	kanban.Get("/cards/:card_id/comments", controllers.GetComments)
 	kanban.Get("/cards/comment/:id", controllers.GetCardComment)
 	kanban.Put("/cards/comment/:id", controllers.UpdateCardComment)
 	kanban.Delete("/cards/comment/:id", controllers.DeleteCardComment)
+	kanban.Get("/cards/comment/:id/revisions", controllers.GetCommentRevisions)
+	kanban.Post("/cards/comment/:id/reactions", controllers.ToggleCommentReaction)
+
+	// Undo route:
+	kanban.Post("/undo/:token", controllers.UndoOperation)
+
+	// Automation routes:
+	kanban.Post("/workspace/:workspace_id/automations", controllers.CreateAutomationRule)
+	kanban.Get("/workspace/:workspace_id/automations", controllers.GetAutomationRules)
+	kanban.Get("/workspace/:workspace_id/automations/logs", controllers.GetAutomationLogs)
+	kanban.Put("/automations/:id", controllers.UpdateAutomationRule)
+	kanban.Delete("/automations/:id", controllers.DeleteAutomationRule)
+
+	// Webhook routes:
+	kanban.Post("/workspace/:workspace_id/webhooks", controllers.CreateWebhook)
+	kanban.Get("/workspace/:workspace_id/webhooks", controllers.GetWebhooks)
+	kanban.Put("/webhooks/:id", controllers.UpdateWebhook)
+	kanban.Delete("/webhooks/:id", controllers.DeleteWebhook)
+	kanban.Get("/webhooks/:id/deliveries", controllers.GetWebhookDeliveries)
+	kanban.Post("/webhooks/:id/test", controllers.TestWebhook)
+
+	// Chat command routes:
+	kanban.Post("/workspace/:workspace_id/chat-commands", controllers.CreateChatCommand)
+	kanban.Get("/workspace/:workspace_id/chat-commands", controllers.GetChatCommands)
+	kanban.Put("/chat-commands/:id", controllers.UpdateChatCommand)
+	kanban.Delete("/chat-commands/:id", controllers.DeleteChatCommand)
 
 	// Subtask routes:
 	kanban.Post("/cards/:card_id/subtask", controllers.CreateSubtask)
 	kanban.Get("/cards/:card_id/subtasks", controllers.GetSubtasks)
 	kanban.Get("/subtask/:id", controllers.GetSubtask)
 	kanban.Put("/subtask/:id", controllers.UpdateSubtask)
+	kanban.Patch("/subtask/:id", controllers.UpdateSubtask)
 	kanban.Delete("/subtask/:id", controllers.DeleteSubtask)
+	kanban.Post("/subtask/:id/convert", controllers.ConvertSubtaskToCard)
+
+	// Checklist routes:
+	kanban.Post("/cards/:card_id/checklist", controllers.CreateChecklist)
+	kanban.Get("/cards/:card_id/checklists", controllers.GetChecklists)
+	kanban.Put("/cards/:card_id/checklists/order", controllers.ReorderChecklists)
+	kanban.Put("/checklists/:id", controllers.UpdateChecklist)
+	kanban.Delete("/checklists/:id", controllers.DeleteChecklist)
+	kanban.Put("/checklists/:id/order", controllers.ReorderChecklistItems)
+
+	// Custom field routes:
+	kanban.Post("/workspace/:workspace_id/custom-fields", controllers.CreateCustomField)
+	kanban.Get("/workspace/:workspace_id/custom-fields", controllers.GetCustomFields)
+	kanban.Put("/custom-fields/:id", controllers.UpdateCustomField)
+	kanban.Delete("/custom-fields/:id", controllers.DeleteCustomField)
+	kanban.Put("/cards/:card_id/custom-fields/:field_id", controllers.SetCardCustomFieldValue)
+	kanban.Delete("/cards/:card_id/custom-fields/:field_id", controllers.DeleteCardCustomFieldValue)
 }
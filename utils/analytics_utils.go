@@ -0,0 +1,399 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// Analytics date ranges.
+const (
+	DefaultAnalyticsRange = 30 * 24 * time.Hour
+	MaxAnalyticsRange     = 366 * 24 * time.Hour
+)
+
+// Analytics CSV reports.
+const (
+	AnalyticsReportCumulativeFlow     = "cumulative_flow"
+	AnalyticsReportCycleTime          = "cycle_time"
+	AnalyticsReportThroughputWeekly   = "throughput_weekly"
+	AnalyticsReportThroughputAssignee = "throughput_assignee"
+)
+
+// analyticsDateFormat is the format of the days and weeks in analytics.
+const analyticsDateFormat = "2006-01-02"
+
+// ErrUnknownAnalyticsReport is returned when a CSV export names an unknown report.
+var ErrUnknownAnalyticsReport = errors.New("report must be cumulative_flow, cycle_time, throughput_weekly or throughput_assignee")
+
+// AnalyticsList is a list of the workspace, as referenced by the cumulative flow.
+type AnalyticsList struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+	Archived  bool   `json:"archived"`
+}
+
+// CumulativeFlowDay counts the cards in each list at the end of a day (UTC), or at the end of
+// the range for its last day.
+type CumulativeFlowDay struct {
+	Date   string       `json:"date"`
+	Counts map[uint]int `json:"counts"` // Cards by list ID
+}
+
+// CardCycleTime describes how long a card completed in the range took. Lead time runs from
+// creation to completion; cycle time from the first move out of the card's first list to
+// completion, and is nil for cards completed without ever moving.
+type CardCycleTime struct {
+	CardID         uint       `json:"card_id"`
+	Title          string     `json:"title"`
+	CreatedAt      time.Time  `json:"created_at"`
+	StartedAt      *time.Time `json:"started_at"`
+	DoneAt         time.Time  `json:"done_at"`
+	LeadTimeHours  float64    `json:"lead_time_hours"`
+	CycleTimeHours *float64   `json:"cycle_time_hours"`
+}
+
+// WeeklyThroughput counts the cards completed in a week, starting on Monday (UTC).
+type WeeklyThroughput struct {
+	WeekStart string `json:"week_start"`
+	Completed int    `json:"completed"`
+}
+
+// AssigneeThroughput counts the completed cards assigned to a user. Unassigned cards are
+// counted under a nil UserID.
+type AssigneeThroughput struct {
+	UserID    *uint  `json:"user_id"`
+	FullName  string `json:"fullname"`
+	Completed int    `json:"completed"`
+}
+
+// AnalyticsSummary sums up the cards completed in the range.
+type AnalyticsSummary struct {
+	CompletedCards       int      `json:"completed_cards"`
+	AvgLeadTimeHours     *float64 `json:"avg_lead_time_hours"`
+	MedianLeadTimeHours  *float64 `json:"median_lead_time_hours"`
+	AvgCycleTimeHours    *float64 `json:"avg_cycle_time_hours"`
+	MedianCycleTimeHours *float64 `json:"median_cycle_time_hours"`
+}
+
+// WorkspaceAnalytics is the flow of the cards of a workspace over a date range.
+type WorkspaceAnalytics struct {
+	From                 time.Time            `json:"from"`
+	To                   time.Time            `json:"to"`
+	Lists                []AnalyticsList      `json:"lists"`
+	CumulativeFlow       []CumulativeFlowDay  `json:"cumulative_flow"`
+	CycleTimes           []CardCycleTime      `json:"cycle_times"`
+	ThroughputByWeek     []WeeklyThroughput   `json:"throughput_by_week"`
+	ThroughputByAssignee []AssigneeThroughput `json:"throughput_by_assignee"`
+	Summary              AnalyticsSummary     `json:"summary"`
+}
+
+// LoadWorkspaceAnalytics computes the analytics of a workspace over [from, to) from the recorded
+// list transitions of its cards. Cards in the trash are left out.
+func LoadWorkspaceAnalytics(workspaceID uint, from, to time.Time) (*WorkspaceAnalytics, error) {
+	var lists []models.BoardList
+	if err := repositories.GetAnalyticsLists(workspaceID, &lists); err != nil {
+		return nil, err
+	}
+	var cards []models.Card
+	if err := repositories.GetAnalyticsCards(workspaceID, to, &cards); err != nil {
+		return nil, err
+	}
+	var transitions []models.CardTransition
+	if err := repositories.GetCardTransitionsByWorkspace(workspaceID, to, &transitions); err != nil {
+		return nil, err
+	}
+	return ComputeWorkspaceAnalytics(lists, cards, transitions, from, to), nil
+}
+
+// ComputeWorkspaceAnalytics computes workspace analytics over [from, to). Transitions must be
+// ordered by card and time. A card is done when it is completed, or otherwise from the moment
+// it entered the completed list it is in.
+func ComputeWorkspaceAnalytics(lists []models.BoardList, cards []models.Card, transitions []models.CardTransition, from, to time.Time) *WorkspaceAnalytics {
+	from, to = from.UTC(), to.UTC()
+	analytics := &WorkspaceAnalytics{
+		From:                 from,
+		To:                   to,
+		Lists:                make([]AnalyticsList, 0, len(lists)),
+		CycleTimes:           []CardCycleTime{},
+		ThroughputByAssignee: []AssigneeThroughput{},
+	}
+
+	completedLists := make(map[uint]bool, len(lists))
+	for _, list := range lists {
+		completedLists[list.ID] = list.CompletedAt != nil
+		analytics.Lists = append(analytics.Lists, AnalyticsList{
+			ID:        list.ID,
+			Title:     list.Title,
+			Completed: list.CompletedAt != nil,
+			Archived:  list.ArchivedAt != nil,
+		})
+	}
+
+	byCard := make(map[uint][]models.CardTransition)
+	for _, transition := range transitions {
+		byCard[transition.CardID] = append(byCard[transition.CardID], transition)
+	}
+
+	analytics.CumulativeFlow = cumulativeFlow(cards, byCard, from, to)
+
+	weekly := make(map[string]int)
+	byAssignee := make(map[uint]*AssigneeThroughput)
+	unassigned := &AssigneeThroughput{FullName: "Unassigned"}
+	var leadTimes, cycleTimes []float64
+	for _, card := range cards {
+		history := byCard[card.ID]
+		doneAt := cardDoneAt(&card, history, completedLists)
+		if doneAt == nil || doneAt.Before(from) || !doneAt.Before(to) {
+			continue
+		}
+
+		cycle := CardCycleTime{
+			CardID:        card.ID,
+			Title:         card.Title,
+			CreatedAt:     card.CreatedAt,
+			DoneAt:        *doneAt,
+			LeadTimeHours: roundHours(doneAt.Sub(card.CreatedAt)),
+		}
+		leadTimes = append(leadTimes, cycle.LeadTimeHours)
+		for _, transition := range history {
+			if transition.FromListID != nil {
+				if !transition.MovedAt.After(*doneAt) {
+					startedAt := transition.MovedAt
+					hours := roundHours(doneAt.Sub(startedAt))
+					cycle.StartedAt, cycle.CycleTimeHours = &startedAt, &hours
+					cycleTimes = append(cycleTimes, hours)
+				}
+				break
+			}
+		}
+		analytics.CycleTimes = append(analytics.CycleTimes, cycle)
+
+		weekly[weekStart(*doneAt).Format(analyticsDateFormat)]++
+		if len(card.Assignees) == 0 {
+			unassigned.Completed++
+		}
+		for _, assignee := range card.Assignees {
+			entry, ok := byAssignee[assignee.UserID]
+			if !ok {
+				userID := assignee.UserID
+				entry = &AssigneeThroughput{UserID: &userID, FullName: assignee.User.FullName}
+				byAssignee[assignee.UserID] = entry
+			}
+			entry.Completed++
+		}
+	}
+
+	sort.Slice(analytics.CycleTimes, func(i, j int) bool {
+		return analytics.CycleTimes[i].DoneAt.Before(analytics.CycleTimes[j].DoneAt)
+	})
+
+	for week := weekStart(from); week.Before(to); week = week.AddDate(0, 0, 7) {
+		key := week.Format(analyticsDateFormat)
+		analytics.ThroughputByWeek = append(analytics.ThroughputByWeek, WeeklyThroughput{WeekStart: key, Completed: weekly[key]})
+	}
+
+	for _, entry := range byAssignee {
+		analytics.ThroughputByAssignee = append(analytics.ThroughputByAssignee, *entry)
+	}
+	sort.Slice(analytics.ThroughputByAssignee, func(i, j int) bool {
+		a, b := analytics.ThroughputByAssignee[i], analytics.ThroughputByAssignee[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		return a.FullName < b.FullName
+	})
+	if unassigned.Completed > 0 {
+		analytics.ThroughputByAssignee = append(analytics.ThroughputByAssignee, *unassigned)
+	}
+
+	analytics.Summary = AnalyticsSummary{
+		CompletedCards:       len(analytics.CycleTimes),
+		AvgLeadTimeHours:     average(leadTimes),
+		MedianLeadTimeHours:  median(leadTimes),
+		AvgCycleTimeHours:    average(cycleTimes),
+		MedianCycleTimeHours: median(cycleTimes),
+	}
+	return analytics
+}
+
+// cumulativeFlow counts the cards in each list at the end of every day of [from, to). Cards
+// count from their creation until they are archived.
+func cumulativeFlow(cards []models.Card, byCard map[uint][]models.CardTransition, from, to time.Time) []CumulativeFlowDay {
+	var days []CumulativeFlowDay
+	var ends []time.Time
+	for day := from.Truncate(24 * time.Hour); day.Before(to); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		if end.After(to) {
+			end = to
+		}
+		days = append(days, CumulativeFlowDay{Date: day.Format(analyticsDateFormat), Counts: map[uint]int{}})
+		ends = append(ends, end)
+	}
+
+	for _, card := range cards {
+		history := byCard[card.ID]
+		next := 0
+		var listID uint
+		for i, end := range ends {
+			if !card.CreatedAt.Before(end) {
+				continue
+			}
+			if card.ArchivedAt != nil && !card.ArchivedAt.After(end) {
+				break
+			}
+			for next < len(history) && history[next].MovedAt.Before(end) {
+				listID = history[next].ToListID
+				next++
+			}
+			if listID != 0 {
+				days[i].Counts[listID]++
+			}
+		}
+	}
+
+	if days == nil {
+		days = []CumulativeFlowDay{}
+	}
+	return days
+}
+
+// cardDoneAt returns when a card was done: its completion time, or otherwise the time it
+// entered the completed list it is in. It returns nil for cards that are not done.
+func cardDoneAt(card *models.Card, history []models.CardTransition, completedLists map[uint]bool) *time.Time {
+	if card.CompletedAt != nil {
+		return card.CompletedAt
+	}
+	if !completedLists[card.ListID] || len(history) == 0 {
+		return nil
+	}
+	last := history[len(history)-1]
+	if last.ToListID != card.ListID {
+		return nil
+	}
+	return &last.MovedAt
+}
+
+// weekStart returns the start of the week (Monday, UTC) of t.
+func weekStart(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// roundHours converts a duration to hours, rounded to two decimals.
+func roundHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
+// average returns the mean of values rounded to two decimals, or nil if there are none.
+func average(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := math.Round(sum/float64(len(values))*100) / 100
+	return &mean
+}
+
+// median returns the median of values rounded to two decimals, or nil if there are none.
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		mid = (sorted[len(sorted)/2-1] + mid) / 2
+	}
+	mid = math.Round(mid*100) / 100
+	return &mid
+}
+
+// WriteAnalyticsCSV writes one report of the analytics as CSV:
+//   - cumulative_flow: a row per day with the number of cards in each list, one column per list
+//   - cycle_time: a row per completed card with its lead and cycle time in hours
+//   - throughput_weekly: a row per week with the number of cards completed
+//   - throughput_assignee: a row per assignee with the number of cards completed
+func WriteAnalyticsCSV(w io.Writer, analytics *WorkspaceAnalytics, report string) error {
+	var rows [][]string
+	switch report {
+	case AnalyticsReportCumulativeFlow:
+		header := []string{"date"}
+		for _, list := range analytics.Lists {
+			header = append(header, csvText(list.Title))
+		}
+		rows = append(rows, header)
+		for _, day := range analytics.CumulativeFlow {
+			row := []string{day.Date}
+			for _, list := range analytics.Lists {
+				row = append(row, strconv.Itoa(day.Counts[list.ID]))
+			}
+			rows = append(rows, row)
+		}
+
+	case AnalyticsReportCycleTime:
+		rows = append(rows, []string{"card_id", "title", "created_at", "started_at", "done_at", "lead_time_hours", "cycle_time_hours"})
+		for _, cycle := range analytics.CycleTimes {
+			startedAt, cycleHours := "", ""
+			if cycle.StartedAt != nil {
+				startedAt = cycle.StartedAt.UTC().Format(time.RFC3339)
+				cycleHours = strconv.FormatFloat(*cycle.CycleTimeHours, 'f', 2, 64)
+			}
+			rows = append(rows, []string{
+				strconv.FormatUint(uint64(cycle.CardID), 10),
+				csvText(cycle.Title),
+				cycle.CreatedAt.UTC().Format(time.RFC3339),
+				startedAt,
+				cycle.DoneAt.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(cycle.LeadTimeHours, 'f', 2, 64),
+				cycleHours,
+			})
+		}
+
+	case AnalyticsReportThroughputWeekly:
+		rows = append(rows, []string{"week_start", "completed"})
+		for _, week := range analytics.ThroughputByWeek {
+			rows = append(rows, []string{week.WeekStart, strconv.Itoa(week.Completed)})
+		}
+
+	case AnalyticsReportThroughputAssignee:
+		rows = append(rows, []string{"user_id", "fullname", "completed"})
+		for _, entry := range analytics.ThroughputByAssignee {
+			userID := ""
+			if entry.UserID != nil {
+				userID = strconv.FormatUint(uint64(*entry.UserID), 10)
+			}
+			rows = append(rows, []string{userID, csvText(entry.FullName), strconv.Itoa(entry.Completed)})
+		}
+
+	default:
+		return ErrUnknownAnalyticsReport
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// csvText keeps user-provided text from being read as a formula by spreadsheet applications.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
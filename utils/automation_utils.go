@@ -0,0 +1,423 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// MaxAutomationDepth is how many times rules may trigger each other, starting from one user
+// action, before the chain is stopped.
+const MaxAutomationDepth = 5
+
+// MaxAutomationActions is the largest number of actions a single rule may have.
+const MaxAutomationActions = 10
+
+// AutomationEvent is something that happened to a card, or for list_completed to a list, that
+// automation rules can react to.
+type AutomationEvent struct {
+	Trigger string
+	CardID  uint // 0 for list_completed, whose rules run on every card of the list
+	ListID  uint // card_moved: the list the card moved to; list_completed: the completed list
+	LabelID uint // label_added: the label that was added
+	ActorID uint // The user whose action caused the event, 0 for the scheduler
+}
+
+// automationRun identifies a rule running on a card.
+type automationRun struct {
+	ruleID uint
+	cardID uint
+}
+
+// queuedAutomationEvent is an event waiting to be dispatched, with the number of rules that
+// led to it.
+type queuedAutomationEvent struct {
+	event AutomationEvent
+	depth int
+}
+
+// IsValidAutomationTrigger reports whether trigger is a supported automation trigger.
+func IsValidAutomationTrigger(trigger string) bool {
+	switch trigger {
+	case models.AutomationTriggerCardCreated, models.AutomationTriggerCardMoved, models.AutomationTriggerCardCompleted,
+		models.AutomationTriggerLabelAdded, models.AutomationTriggerSubtasksCompleted, models.AutomationTriggerListCompleted,
+		models.AutomationTriggerDeadlinePassed, models.AutomationTriggerBlockedCardMoved:
+		return true
+	}
+	return false
+}
+
+// ValidateAutomationRule checks a rule's trigger and actions, and that the lists, labels and
+// users it refers to belong to its workspace.
+func ValidateAutomationRule(rule *models.AutomationRule) error {
+	if !IsValidAutomationTrigger(rule.Trigger) {
+		return errors.New("trigger must be card_created, card_moved, card_completed, label_added, subtasks_completed, list_completed, deadline_passed or blocked_card_moved")
+	}
+	if rule.TriggerListID != nil && !listInWorkspace(*rule.TriggerListID, rule.WorkspaceID) {
+		return errors.New("trigger_list_id must be a list in this workspace")
+	}
+	if rule.TriggerLabelID != nil {
+		if rule.Trigger != models.AutomationTriggerLabelAdded {
+			return errors.New("trigger_label_id can only be used with the label_added trigger")
+		}
+		if !labelInWorkspace(*rule.TriggerLabelID, rule.WorkspaceID) {
+			return errors.New("trigger_label_id must be a label in this workspace")
+		}
+	}
+
+	if len(rule.Actions) == 0 || len(rule.Actions) > MaxAutomationActions {
+		return fmt.Errorf("a rule must have between 1 and %d actions", MaxAutomationActions)
+	}
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case models.AutomationActionCompleteSubtasks, models.AutomationActionCompleteCard,
+			models.AutomationActionArchiveCard, models.AutomationActionAssignOwner:
+		case models.AutomationActionMoveToList:
+			if !listInWorkspace(action.ListID, rule.WorkspaceID) {
+				return errors.New("move_to_list needs a list_id in this workspace")
+			}
+		case models.AutomationActionAddLabel:
+			if !labelInWorkspace(action.LabelID, rule.WorkspaceID) {
+				return errors.New("add_label needs a label_id in this workspace")
+			}
+		case models.AutomationActionAssignUser:
+			if _, err := CheckRoleInWorkspace(action.UserID, rule.WorkspaceID); err != nil {
+				return errors.New("assign_user needs the user_id of a workspace member")
+			}
+		default:
+			return fmt.Errorf("unknown action type %q", action.Type)
+		}
+	}
+	return nil
+}
+
+// DispatchAutomationEvent runs the enabled rules of the card's workspace that match the event,
+// then the rules matching the events those rules cause, and so on. A rule runs at most once per
+// card for one dispatch, and the chain stops after MaxAutomationDepth levels; runs stopped this
+// way are logged as skipped. Each run is recorded in the automation log, and errors are logged
+// rather than returned so that automations never fail the action that triggered them.
+func DispatchAutomationEvent(event AutomationEvent) {
+	ran := make(map[automationRun]bool)
+	queue := []queuedAutomationEvent{{event: event}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		queue = append(queue, runAutomationEvent(next.event, next.depth, ran)...)
+	}
+}
+
+// DispatchSubtaskDone dispatches subtasks_completed if the card has no open subtasks left.
+func DispatchSubtaskDone(cardID, actorID uint) {
+	open, err := repositories.CountOpenSubtasks(cardID)
+	if err != nil {
+		log.Println("Error counting open subtasks:", err)
+		return
+	}
+	if open == 0 {
+		DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerSubtasksCompleted, CardID: cardID, ActorID: actorID})
+	}
+}
+
+// StartAutomationScheduler fires the deadline_passed trigger for overdue cards, checking every
+// AUTOMATION_CHECK_INTERVAL (default 5m). A rule fires once per card and deadline.
+func StartAutomationScheduler() {
+	interval := env.Duration("AUTOMATION_CHECK_INTERVAL", 5*time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			dispatchDeadlineAutomations(time.Now())
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Automation scheduler started (interval %s)", interval)
+}
+
+// dispatchDeadlineAutomations dispatches deadline_passed for the overdue cards of every
+// workspace that has a deadline_passed rule.
+func dispatchDeadlineAutomations(now time.Time) {
+	var rules []models.AutomationRule
+	if err := repositories.GetEnabledAutomationRulesByTrigger(models.AutomationTriggerDeadlinePassed, &rules); err != nil {
+		log.Println("Error fetching deadline automation rules:", err)
+		return
+	}
+
+	seen := make(map[uint]bool)
+	for _, rule := range rules {
+		if seen[rule.WorkspaceID] {
+			continue
+		}
+		seen[rule.WorkspaceID] = true
+
+		var cards []models.Card
+		if err := repositories.GetOverdueCardsByWorkspace(rule.WorkspaceID, now, &cards); err != nil {
+			log.Printf("Error fetching overdue cards of workspace %d: %v", rule.WorkspaceID, err)
+			continue
+		}
+		for _, card := range cards {
+			DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerDeadlinePassed, CardID: card.ID})
+		}
+	}
+}
+
+// runAutomationEvent runs the rules matching one event and returns the events they caused.
+func runAutomationEvent(event AutomationEvent, depth int, ran map[automationRun]bool) []queuedAutomationEvent {
+	cardIDs := []uint{event.CardID}
+	if event.Trigger == models.AutomationTriggerListCompleted {
+		var cards []models.Card
+		if err := repositories.GetCardsByListID(event.ListID, false, &cards); err != nil {
+			log.Println("Error fetching cards for automation:", err)
+			return nil
+		}
+		cardIDs = cardIDs[:0]
+		for _, card := range cards {
+			cardIDs = append(cardIDs, card.ID)
+		}
+	}
+
+	var followUps []queuedAutomationEvent
+	rulesByWorkspace := make(map[uint][]models.AutomationRule)
+	for _, cardID := range cardIDs {
+		card, err := loadAutomationCard(cardID)
+		if err != nil {
+			continue
+		}
+
+		workspaceID := card.List.WorkspaceID
+		rules, ok := rulesByWorkspace[workspaceID]
+		if !ok {
+			if err := repositories.GetEnabledAutomationRules(workspaceID, event.Trigger, &rules); err != nil {
+				log.Println("Error fetching automation rules:", err)
+				return followUps
+			}
+			rulesByWorkspace[workspaceID] = rules
+		}
+
+		for _, rule := range rules {
+			// Reload so each rule sees the changes made by the rules before it.
+			if card, err = loadAutomationCard(cardID); err != nil {
+				break
+			}
+			if !automationRuleMatches(&rule, card, event) {
+				continue
+			}
+
+			run := automationRun{ruleID: rule.ID, cardID: card.ID}
+			if ran[run] {
+				logAutomationRun(&rule, card.ID, event.Trigger, depth, models.AutomationStatusSkipped, "Loop detected: the rule already ran on this card in this chain")
+				continue
+			}
+			if depth >= MaxAutomationDepth {
+				logAutomationRun(&rule, card.ID, event.Trigger, depth, models.AutomationStatusSkipped,
+					fmt.Sprintf("Stopped after %d chained rules", MaxAutomationDepth))
+				continue
+			}
+			ran[run] = true
+
+			actorID := event.ActorID
+			if actorID == 0 {
+				actorID = rule.CreatedByID
+			}
+			events, done, err := runAutomationActions(&rule, card, actorID)
+			for _, e := range events {
+				followUps = append(followUps, queuedAutomationEvent{event: e, depth: depth + 1})
+			}
+			if err != nil {
+				logAutomationRun(&rule, card.ID, event.Trigger, depth, models.AutomationStatusFailed, err.Error())
+				continue
+			}
+			logAutomationRun(&rule, card.ID, event.Trigger, depth, models.AutomationStatusSuccess, "Ran "+strings.Join(done, ", "))
+		}
+	}
+	return followUps
+}
+
+// loadAutomationCard loads a card with its associations and its list.
+func loadAutomationCard(id uint) (*models.Card, error) {
+	var card models.Card
+	if err := repositories.GetCardByID(id, &card); err != nil {
+		return nil, err
+	}
+	if err := repositories.GetBoardListByID(card.ListID, &card.List); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// automationRuleMatches reports whether a rule's conditions hold for a card and event.
+// deadline_passed rules only match cards they have not already run on since the deadline.
+func automationRuleMatches(rule *models.AutomationRule, card *models.Card, event AutomationEvent) bool {
+	if rule.TriggerListID != nil && *rule.TriggerListID != card.ListID {
+		return false
+	}
+	if rule.TriggerLabelID != nil && *rule.TriggerLabelID != event.LabelID {
+		return false
+	}
+	if rule.Trigger == models.AutomationTriggerDeadlinePassed {
+		if card.Deadline == nil {
+			return false
+		}
+		if done, err := repositories.HasAutomationRunSince(rule.ID, card.ID, *card.Deadline); err != nil || done {
+			return false
+		}
+	}
+	return true
+}
+
+// runAutomationActions runs the actions of a rule on a card in order, stopping at the first
+// that fails. Actions that already ran are not rolled back. It returns the events caused by
+// the actions and the types of the actions that ran.
+func runAutomationActions(rule *models.AutomationRule, card *models.Card, actorID uint) ([]AutomationEvent, []string, error) {
+	var events []AutomationEvent
+	var done []string
+	for _, action := range rule.Actions {
+		caused, err := runAutomationAction(action, card, actorID)
+		events = append(events, caused...)
+		if err != nil {
+			return events, done, fmt.Errorf("%s: %w", action.Type, err)
+		}
+		done = append(done, action.Type)
+	}
+	return events, done, nil
+}
+
+// runAutomationAction runs one action on a card, keeping the card up to date, and returns the
+// events it caused. Actions that would not change anything are no-ops.
+func runAutomationAction(action models.AutomationAction, card *models.Card, actorID uint) ([]AutomationEvent, error) {
+	now := time.Now()
+	workspaceID := card.List.WorkspaceID
+
+	switch action.Type {
+	case models.AutomationActionCompleteSubtasks:
+		open, err := repositories.CountOpenSubtasks(card.ID)
+		if err != nil || open == 0 {
+			return nil, err
+		}
+		if err := repositories.CompleteSubtasksByCard(card.ID); err != nil {
+			return nil, err
+		}
+		for i := range card.Subtasks {
+			card.Subtasks[i].IsDone = true
+		}
+		return []AutomationEvent{{Trigger: models.AutomationTriggerSubtasksCompleted, CardID: card.ID, ActorID: actorID}}, nil
+
+	case models.AutomationActionCompleteCard:
+		if card.CompletedAt != nil {
+			return nil, nil
+		}
+		if err := repositories.CompleteCard(card, now, NewNextOccurrence(card, now)); errors.Is(err, repositories.ErrCardAlreadyCompleted) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		card.CompletedAt = &now
+		return []AutomationEvent{{Trigger: models.AutomationTriggerCardCompleted, CardID: card.ID, ActorID: actorID}}, nil
+
+	case models.AutomationActionArchiveCard:
+		if card.ArchivedAt != nil {
+			return nil, nil
+		}
+		if err := repositories.SetCardArchivedAt(card.ID, &now); err != nil {
+			return nil, err
+		}
+		card.ArchivedAt = &now
+		return nil, nil
+
+	case models.AutomationActionMoveToList:
+		if card.ListID == action.ListID {
+			return nil, nil
+		}
+		if !listInWorkspace(action.ListID, workspaceID) {
+			return nil, errors.New("the list is no longer in this workspace")
+		}
+		if _, err := CheckWIPLimit(action.ListID, 1); err != nil {
+			return nil, err
+		}
+		if err := repositories.MoveCard(card.ID, action.ListID); err != nil {
+			return nil, err
+		}
+		fromListID := card.ListID
+		card.ListID = action.ListID
+		EmitCardMovedWebhookEvent(card, fromListID)
+		events := []AutomationEvent{{Trigger: models.AutomationTriggerCardMoved, CardID: card.ID, ListID: action.ListID, ActorID: actorID}}
+		if blockers, err := openBlockersInDoneList(card); err == nil && len(blockers) > 0 {
+			events = append(events, AutomationEvent{Trigger: models.AutomationTriggerBlockedCardMoved, CardID: card.ID, ListID: action.ListID, ActorID: actorID})
+		}
+		return events, nil
+
+	case models.AutomationActionAddLabel:
+		if !labelInWorkspace(action.LabelID, workspaceID) {
+			return nil, errors.New("the label is no longer in this workspace")
+		}
+		onCard, err := repositories.IsLabelOnCard(card.ID, action.LabelID)
+		if err != nil || onCard {
+			return nil, err
+		}
+		if err := repositories.CreateCardLabel(&models.CardLabel{CardID: card.ID, LabelID: action.LabelID}); err != nil {
+			return nil, err
+		}
+		return []AutomationEvent{{Trigger: models.AutomationTriggerLabelAdded, CardID: card.ID, LabelID: action.LabelID, ActorID: actorID}}, nil
+
+	case models.AutomationActionAssignUser, models.AutomationActionAssignOwner:
+		userID := action.UserID
+		if action.Type == models.AutomationActionAssignOwner {
+			ownerID, err := repositories.GetWorkspaceOwnerID(workspaceID)
+			if err != nil {
+				return nil, err
+			}
+			userID = ownerID
+		} else if _, err := CheckRoleInWorkspace(userID, workspaceID); err != nil {
+			return nil, errors.New("the user is no longer a member of this workspace")
+		}
+
+		var existing models.CardAssignee
+		if err := repositories.GetCardAssignee(card.ID, userID, &existing); err == nil {
+			return nil, nil
+		}
+		if err := repositories.CreateCardAssignee(&models.CardAssignee{CardID: card.ID, UserID: userID}); err != nil {
+			return nil, err
+		}
+		if err := NotifyCardAssigned(card, userID, actorID); err != nil {
+			log.Println("Error notifying assignee:", err)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown action type %q", action.Type)
+}
+
+// logAutomationRun records a rule execution in the automation log.
+func logAutomationRun(rule *models.AutomationRule, cardID uint, trigger string, depth int, status, message string) {
+	entry := models.AutomationLog{
+		RuleID:      rule.ID,
+		WorkspaceID: rule.WorkspaceID,
+		CardID:      cardID,
+		Trigger:     trigger,
+		Status:      status,
+		Message:     message,
+		Depth:       depth,
+	}
+	if err := repositories.CreateAutomationLog(&entry); err != nil {
+		log.Println("Error recording automation log:", err)
+	}
+}
+
+// listInWorkspace reports whether a list exists and belongs to a workspace.
+func listInWorkspace(listID, workspaceID uint) bool {
+	listWorkspaceID, err := repositories.GetWorkspaceIDByListID(listID)
+	return err == nil && listWorkspaceID == workspaceID
+}
+
+// labelInWorkspace reports whether a label exists and belongs to a workspace.
+func labelInWorkspace(labelID, workspaceID uint) bool {
+	var label models.Label
+	return repositories.GetLabelByID(labelID, &label) == nil && label.WorkspaceID == workspaceID
+}
@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// MaxBulkCards is the largest number of cards a single bulk operation may change.
+const MaxBulkCards = 100
+
+// ErrBulkTargetNotFound is returned when the list or label of a bulk operation does not exist.
+var ErrBulkTargetNotFound = errors.New("bulk operation target not found")
+
+// BulkCardResult reports the outcome of a bulk operation for one card.
+type BulkCardResult struct {
+	CardID  uint   `json:"card_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"` // move: the list is over its soft WIP limit or the card is still blocked by open cards
+}
+
+// ApplyBulkCardOperation applies a bulk operation on behalf of a user. Each card is checked on
+// its own: the user must be an editor, admin or owner of the card's workspace, and the target
+// list, label or assignee must belong to that same workspace. Moves that would take the target
+// list over a hard WIP limit fail for the cards that do not fit. Cards that pass are changed in a
+// single transaction; the others are reported as failed and left untouched. An error is only
+// returned if the transaction fails, in which case no card is changed.
+func ApplyBulkCardOperation(actorID uint, op string, cardIDs []uint, change repositories.BulkCardChange) ([]BulkCardResult, error) {
+	var cards []models.Card
+	if err := repositories.GetCardsWithListByIDs(cardIDs, &cards); err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Card, len(cards))
+	for i := range cards {
+		byID[cards[i].ID] = &cards[i]
+	}
+
+	targetWorkspaceID, err := bulkTargetWorkspace(op, change)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[uint]string)
+	members := make(map[uint]bool)
+	results := make([]BulkCardResult, 0, len(cardIDs))
+	var applicable []uint
+	seen := make(map[uint]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := BulkCardResult{CardID: id}
+		card, ok := byID[id]
+		if !ok {
+			result.Error = "Card not found"
+			results = append(results, result)
+			continue
+		}
+
+		workspaceID := card.List.WorkspaceID
+		role, checked := roles[workspaceID]
+		if !checked {
+			role, _ = CheckRoleInWorkspace(actorID, workspaceID)
+			roles[workspaceID] = role
+		}
+
+		switch {
+		case role == "":
+			result.Error = "You do not have access to this workspace"
+		case !IsEditorAdminOwner(role):
+			result.Error = "Insufficient permission to update card"
+		case targetWorkspaceID != 0 && targetWorkspaceID != workspaceID:
+			result.Error = "Target must belong to the card's workspace"
+		case op == repositories.BulkCardAssign && !isWorkspaceMember(members, change.UserID, workspaceID):
+			result.Error = "User is not a member of the card's workspace"
+		default:
+			result.Success = true
+			applicable = append(applicable, id)
+		}
+		results = append(results, result)
+	}
+
+	var wipWarnings map[uint]string
+	if op == repositories.BulkCardMove && len(applicable) > 0 {
+		if applicable, wipWarnings, err = limitBulkMove(results, applicable, byID, change.ListID); err != nil {
+			return nil, err
+		}
+	}
+
+	var alreadyAssigned []uint
+	if op == repositories.BulkCardAssign && len(applicable) > 0 {
+		if alreadyAssigned, err = repositories.GetCardIDsAssignedToUser(change.UserID, applicable); err != nil {
+			return nil, err
+		}
+	}
+
+	var alreadyLabelled []uint
+	if op == repositories.BulkCardLabel && len(applicable) > 0 {
+		if alreadyLabelled, err = repositories.GetCardIDsWithLabel(change.LabelID, applicable); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repositories.ApplyBulkCardChange(op, applicable, change, time.Now()); err != nil {
+		return nil, err
+	}
+	warnings := dispatchBulkAutomations(op, applicable, byID, alreadyLabelled, change, actorID)
+	for i := range results {
+		results[i].Warning = JoinWarnings(wipWarnings[results[i].CardID], warnings[results[i].CardID])
+	}
+
+	if op == repositories.BulkCardAssign {
+		skip := make(map[uint]bool, len(alreadyAssigned))
+		for _, id := range alreadyAssigned {
+			skip[id] = true
+		}
+		for _, id := range applicable {
+			if skip[id] {
+				continue
+			}
+			if err := NotifyCardAssigned(byID[id], change.UserID, actorID); err != nil {
+				log.Println("Error notifying assignee:", err)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// dispatchBulkAutomations emits the webhook and automation events caused by a bulk move or label.
+// Cards that were already in the list or already had the label are left out. It returns the
+// warnings for blocked cards moved to a completed list, by card ID.
+func dispatchBulkAutomations(op string, cardIDs []uint, byID map[uint]*models.Card, alreadyLabelled []uint, change repositories.BulkCardChange, actorID uint) map[uint]string {
+	warnings := make(map[uint]string)
+	skip := make(map[uint]bool, len(alreadyLabelled))
+	for _, id := range alreadyLabelled {
+		skip[id] = true
+	}
+
+	for _, id := range cardIDs {
+		switch {
+		case op == repositories.BulkCardMove && byID[id].ListID != change.ListID:
+			moved := *byID[id]
+			moved.ListID = change.ListID
+			EmitCardMovedWebhookEvent(&moved, byID[id].ListID)
+			DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerCardMoved, CardID: id, ListID: change.ListID, ActorID: actorID})
+			if blockers := CheckBlockedMove(&moved, actorID); len(blockers) > 0 {
+				warnings[id] = BlockedMoveWarning(blockers)
+			}
+		case op == repositories.BulkCardLabel && !skip[id]:
+			DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerLabelAdded, CardID: id, LabelID: change.LabelID, ActorID: actorID})
+		}
+	}
+	return warnings
+}
+
+// limitBulkMove applies the WIP limit of the target list to a bulk move, in the order of the
+// cards. Cards that do not fit under a hard limit are marked as failed in results and left out
+// of the returned IDs; under a soft limit they are moved with a warning, returned by card ID.
+func limitBulkMove(results []BulkCardResult, cardIDs []uint, byID map[uint]*models.Card, listID uint) ([]uint, map[uint]string, error) {
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(listID, &list); err != nil {
+		return nil, nil, err
+	}
+	remaining, err := WIPCapacity(&list)
+	if err != nil || remaining < 0 {
+		return cardIDs, nil, err
+	}
+
+	index := make(map[uint]int, len(results))
+	for i, result := range results {
+		index[result.CardID] = i
+	}
+
+	kept := make([]uint, 0, len(cardIDs))
+	warnings := make(map[uint]string)
+	for _, id := range cardIDs {
+		switch {
+		case byID[id].ListID == listID:
+		case remaining > 0:
+			remaining--
+		case list.WIPLimitMode == models.WIPLimitHard:
+			result := &results[index[id]]
+			result.Success, result.Error = false, WIPLimitError(&list).Error()
+			continue
+		default:
+			warnings[id] = WIPLimitWarning(&list)
+		}
+		kept = append(kept, id)
+	}
+	return kept, warnings, nil
+}
+
+// bulkTargetWorkspace returns the workspace of the list or label a bulk operation targets,
+// or 0 if the operation has no workspace-bound target.
+func bulkTargetWorkspace(op string, change repositories.BulkCardChange) (uint, error) {
+	switch op {
+	case repositories.BulkCardMove:
+		workspaceID, err := repositories.GetWorkspaceIDByListID(change.ListID)
+		if err != nil {
+			return 0, ErrBulkTargetNotFound
+		}
+		return workspaceID, nil
+	case repositories.BulkCardLabel:
+		var label models.Label
+		if err := repositories.GetLabelByID(change.LabelID, &label); err != nil {
+			return 0, ErrBulkTargetNotFound
+		}
+		return label.WorkspaceID, nil
+	}
+	return 0, nil
+}
+
+// isWorkspaceMember reports whether a user belongs to a workspace, caching the answer.
+func isWorkspaceMember(cache map[uint]bool, userID, workspaceID uint) bool {
+	member, ok := cache[workspaceID]
+	if !ok {
+		_, err := CheckRoleInWorkspace(userID, workspaceID)
+		member = err == nil
+		cache[workspaceID] = member
+	}
+	return member
+}
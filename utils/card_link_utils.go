@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// ErrCardLinkExists is returned when the same link between two cards already exists.
+var ErrCardLinkExists = errors.New("these cards are already linked this way")
+
+// ErrCardLinkCycle is returned when a blocking link would make a card (indirectly) block itself.
+var ErrCardLinkCycle = errors.New("this link would create a blocking cycle")
+
+// IsValidCardLinkType reports whether linkType is a card link type or an inverse type.
+func IsValidCardLinkType(linkType string) bool {
+	switch linkType {
+	case models.CardLinkBlocks, models.CardLinkBlockedBy, models.CardLinkRelatesTo,
+		models.CardLinkDuplicates, models.CardLinkDuplicatedBy:
+		return true
+	}
+	return false
+}
+
+// NewCardLink builds the link "card <linkType> other card" in its stored direction: inverse
+// types are turned around, so "A blocked_by B" is stored as "B blocks A". It checks that both
+// cards are different cards of the same workspace, that the link does not exist yet (in either
+// direction for relates_to) and that a blocking link does not create a cycle.
+func NewCardLink(card, other *models.Card, linkType string, createdByID uint) (*models.CardLink, error) {
+	if !IsValidCardLinkType(linkType) {
+		return nil, errors.New("type must be blocks, blocked_by, relates_to, duplicates or duplicated_by")
+	}
+	if card.ID == other.ID {
+		return nil, errors.New("a card cannot be linked to itself")
+	}
+
+	workspaceID, err := repositories.GetWorkspaceIDByListID(card.ListID)
+	if err != nil {
+		return nil, err
+	}
+	otherWorkspaceID, err := repositories.GetWorkspaceIDByListID(other.ListID)
+	if err != nil || otherWorkspaceID != workspaceID {
+		return nil, errors.New("linked cards must be in the same workspace")
+	}
+
+	link := &models.CardLink{CardID: card.ID, LinkedCardID: other.ID, Type: linkType, CreatedByID: createdByID}
+	switch linkType {
+	case models.CardLinkBlockedBy:
+		link.CardID, link.LinkedCardID, link.Type = other.ID, card.ID, models.CardLinkBlocks
+	case models.CardLinkDuplicatedBy:
+		link.CardID, link.LinkedCardID, link.Type = other.ID, card.ID, models.CardLinkDuplicates
+	}
+
+	exists, err := repositories.CardLinkExists(link.CardID, link.LinkedCardID, link.Type)
+	if err == nil && !exists && link.Type == models.CardLinkRelatesTo {
+		exists, err = repositories.CardLinkExists(link.LinkedCardID, link.CardID, link.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrCardLinkExists
+	}
+
+	if link.Type == models.CardLinkBlocks {
+		cycle, err := blocksTransitively(link.LinkedCardID, link.CardID)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, ErrCardLinkCycle
+		}
+	}
+	return link, nil
+}
+
+// blocksTransitively reports whether card from blocks card to, directly or through other cards.
+func blocksTransitively(from, to uint) (bool, error) {
+	visited := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 {
+		blocked, err := repositories.GetCardIDsBlockedBy(frontier)
+		if err != nil {
+			return false, err
+		}
+		frontier = frontier[:0]
+		for _, id := range blocked {
+			if id == to {
+				return true, nil
+			}
+			if !visited[id] {
+				visited[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// CheckBlockedMove returns the open cards blocking a card that has been moved to a completed
+// list, and dispatches the blocked_card_moved automation trigger for it. It returns nil if the
+// list is not completed or the card is not blocked.
+func CheckBlockedMove(card *models.Card, actorID uint) []models.Card {
+	blockers, err := openBlockersInDoneList(card)
+	if err != nil {
+		log.Println("Error checking card blockers:", err)
+		return nil
+	}
+	if len(blockers) > 0 {
+		DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerBlockedCardMoved, CardID: card.ID, ListID: card.ListID, ActorID: actorID})
+	}
+	return blockers
+}
+
+// BlockedMoveWarning describes the open blockers of a card moved to a completed list.
+func BlockedMoveWarning(blockers []models.Card) string {
+	if len(blockers) == 1 {
+		return fmt.Sprintf("This card is still blocked by %q", blockers[0].Title)
+	}
+	return fmt.Sprintf("This card is still blocked by %d open cards", len(blockers))
+}
+
+// openBlockersInDoneList returns the open blockers of a card if its list is completed.
+func openBlockersInDoneList(card *models.Card) ([]models.Card, error) {
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(card.ListID, &list); err != nil {
+		return nil, err
+	}
+	if list.CompletedAt == nil {
+		return nil, nil
+	}
+
+	var blockers []models.Card
+	if err := repositories.GetOpenBlockers(card.ID, &blockers); err != nil {
+		return nil, err
+	}
+	return blockers, nil
+}
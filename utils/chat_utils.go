@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// chatRequestMaxAge is how old a signed slash command request may be before it is rejected
+// as a possible replay.
+const chatRequestMaxAge = 5 * time.Minute
+
+// maxChatExcerpt is how many characters of a comment are quoted in a chat message.
+const maxChatExcerpt = 200
+
+// chatEventData holds the fields of webhook event data that chat messages use.
+type chatEventData struct {
+	Card *struct {
+		ID          uint       `json:"id"`
+		Title       string     `json:"title"`
+		ListID      uint       `json:"list_id"`
+		CompletedAt *time.Time `json:"completed_at"`
+		ArchivedAt  *time.Time `json:"archived_at"`
+	} `json:"card"`
+	FromListID uint   `json:"from_list_id"`
+	ToListID   uint   `json:"to_list_id"`
+	CardTitle  string `json:"card_title"`
+	Comment    *struct {
+		Comment string `json:"comment"`
+		User    struct {
+			FullName string `json:"fullname"`
+		} `json:"user"`
+	} `json:"comment"`
+	User *struct {
+		FullName string `json:"fullname"`
+	} `json:"user"`
+	Role string `json:"role"`
+}
+
+// IsValidWebhookFormat reports whether format is a supported webhook payload format.
+func IsValidWebhookFormat(format string) bool {
+	switch format {
+	case models.WebhookFormatJSON, models.WebhookFormatSlack, models.WebhookFormatDiscord:
+		return true
+	}
+	return false
+}
+
+// FormatChatMessage renders an event as a one-line chat message.
+func FormatChatMessage(event string, data interface{}) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	var d chatEventData
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return "", err
+	}
+
+	switch event {
+	case models.WebhookEventCardCreated:
+		if d.Card != nil {
+			return fmt.Sprintf("New card %q in %q", d.Card.Title, chatListTitle(d.Card.ListID)), nil
+		}
+	case models.WebhookEventCardMoved:
+		if d.Card != nil {
+			return fmt.Sprintf("%q moved from %q to %q", d.Card.Title, chatListTitle(d.FromListID), chatListTitle(d.ToListID)), nil
+		}
+	case models.WebhookEventCardUpdated:
+		if d.Card != nil {
+			switch {
+			case d.Card.ArchivedAt != nil:
+				return fmt.Sprintf("%q was archived", d.Card.Title), nil
+			case d.Card.CompletedAt != nil:
+				return fmt.Sprintf("%q was completed", d.Card.Title), nil
+			}
+			return fmt.Sprintf("%q was updated", d.Card.Title), nil
+		}
+	case models.WebhookEventCommentAdded:
+		if d.Comment != nil {
+			excerpt := []rune(d.Comment.Comment)
+			if len(excerpt) > maxChatExcerpt {
+				excerpt = append(excerpt[:maxChatExcerpt], '…')
+			}
+			return fmt.Sprintf("%s commented on %q: %s", d.Comment.User.FullName, d.CardTitle, string(excerpt)), nil
+		}
+	case models.WebhookEventMemberShared:
+		if d.User != nil {
+			return fmt.Sprintf("%s joined the workspace as %s", d.User.FullName, d.Role), nil
+		}
+	case models.WebhookEventPing:
+		return "Test message from KelarIn: this webhook is set up correctly", nil
+	}
+	return "", fmt.Errorf("cannot format %s event as a chat message", event)
+}
+
+// webhookBody returns the body posted to a webhook of the given format: the event payload for
+// JSON webhooks, or a chat message for Slack and Discord webhooks.
+func webhookBody(format string, payload *WebhookPayload) ([]byte, error) {
+	if format == models.WebhookFormatJSON || format == "" {
+		return json.Marshal(payload)
+	}
+
+	text, err := FormatChatMessage(payload.Event, payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	if format == models.WebhookFormatDiscord {
+		return json.Marshal(map[string]string{"content": text})
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// chatListTitle returns the title of a list, or a placeholder if it no longer exists.
+func chatListTitle(listID uint) string {
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(listID, &list); err != nil {
+		return "a deleted list"
+	}
+	return list.Title
+}
+
+// ValidateChatSigningKey checks the signing key of a chat slash command: any non-empty Slack
+// signing secret, or a hex-encoded Ed25519 public key for Discord.
+func ValidateChatSigningKey(provider, key string) error {
+	switch provider {
+	case models.ChatProviderSlack:
+		if key == "" || len(key) > 255 {
+			return errors.New("signing_key must be the Slack app's signing secret")
+		}
+	case models.ChatProviderDiscord:
+		if decoded, err := hex.DecodeString(key); err != nil || len(decoded) != ed25519.PublicKeySize {
+			return errors.New("signing_key must be the Discord application's hex-encoded public key")
+		}
+	default:
+		return errors.New("provider must be slack or discord")
+	}
+	return nil
+}
+
+// VerifySlackRequest checks a Slack request signature: X-Slack-Signature must be "v0=" followed
+// by the hex HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret, and
+// X-Slack-Request-Timestamp must be recent.
+func VerifySlackRequest(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	if !chatTimestampFresh(timestamp, now) {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// VerifyDiscordRequest checks a Discord interaction signature: X-Signature-Ed25519 must be the
+// hex Ed25519 signature of "<timestamp><body>" by the application's public key, and
+// X-Signature-Timestamp must be recent.
+func VerifyDiscordRequest(publicKey, timestamp, signature string, body []byte, now time.Time) bool {
+	if !chatTimestampFresh(timestamp, now) {
+		return false
+	}
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(key), append([]byte(timestamp), body...), sig)
+}
+
+// chatTimestampFresh reports whether a Unix timestamp is within chatRequestMaxAge of now.
+func chatTimestampFresh(timestamp string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	return age <= chatRequestMaxAge && age >= -chatRequestMaxAge
+}
+
+// ParseChatCardText splits slash command text of the form "title | description".
+func ParseChatCardText(text string) (title, description string) {
+	title, description, _ = strings.Cut(text, "|")
+	return strings.TrimSpace(title), strings.TrimSpace(description)
+}
+
+// CreateCardFromChat creates a card in the list of a chat slash command. Like cards created in
+// the app, it emits webhook events and runs automation rules, on behalf of the user who set up
+// the command. The returned errors are safe to show in the chat.
+func CreateCardFromChat(command *models.ChatCommand, title, description string) (*models.Card, error) {
+	if title == "" {
+		return nil, errors.New("a card title is required")
+	}
+	if err := ValidateLength("Title", title, MaxCardTitleLength); err != nil {
+		return nil, err
+	}
+	if err := ValidateLength("Description", description, MaxCardDescriptionLength); err != nil {
+		return nil, err
+	}
+
+	if _, err := CheckWIPLimit(command.ListID, 1); errors.Is(err, ErrWIPLimitReached) {
+		return nil, err
+	} else if err != nil {
+		log.Println("Error checking WIP limit:", err)
+		return nil, errors.New("failed to create card")
+	}
+
+	now := time.Now()
+	card := models.Card{
+		Title:       title,
+		Description: description,
+		ListID:      command.ListID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := repositories.CreateCard(&card); errors.Is(err, ErrWIPLimitReached) {
+		return nil, err
+	} else if err != nil {
+		log.Println("Error creating card from chat:", err)
+		return nil, errors.New("failed to create card")
+	}
+
+	EmitCardWebhookEvent(models.WebhookEventCardCreated, &card)
+	DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerCardCreated, CardID: card.ID, ListID: card.ListID, ActorID: command.CreatedByID})
+	return &card, nil
+}
@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kelarin-backend/models"
+)
+
+func TestVerifySlackRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte("text=Fix+login")
+	sign := func(secret, timestamp string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + timestamp + ":" + string(body)))
+		return "v0=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	fresh := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		body      []byte
+		want      bool
+	}{
+		{"valid", fresh, sign("secret", fresh), body, true},
+		{"wrong secret", fresh, sign("other", fresh), body, false},
+		{"tampered body", fresh, sign("secret", fresh), []byte("text=Drop+tables"), false},
+		{"malformed signature", fresh, "v0=zz", body, false},
+		{"stale timestamp", stale, sign("secret", stale), body, false},
+		{"missing timestamp", "", sign("secret", ""), body, false},
+	}
+	for _, tt := range tests {
+		if got := VerifySlackRequest("secret", tt.timestamp, tt.signature, tt.body, now); got != tt.want {
+			t.Errorf("%s: VerifySlackRequest() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyDiscordRequest(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":2}`)
+	sign := func(key ed25519.PrivateKey, timestamp string) string {
+		return hex.EncodeToString(ed25519.Sign(key, append([]byte(timestamp), body...)))
+	}
+	fresh := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(6*time.Minute).Unix(), 10)
+	key := hex.EncodeToString(publicKey)
+
+	tests := []struct {
+		name      string
+		key       string
+		timestamp string
+		signature string
+		want      bool
+	}{
+		{"valid", key, fresh, sign(privateKey, fresh), true},
+		{"wrong key", key, fresh, sign(otherKey, fresh), false},
+		{"malformed signature", key, fresh, "not-hex", false},
+		{"malformed public key", "abc", fresh, sign(privateKey, fresh), false},
+		{"stale timestamp", key, stale, sign(privateKey, stale), false},
+	}
+	for _, tt := range tests {
+		if got := VerifyDiscordRequest(tt.key, tt.timestamp, tt.signature, body, now); got != tt.want {
+			t.Errorf("%s: VerifyDiscordRequest() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatChatMessage(t *testing.T) {
+	now := time.Now()
+	card := &models.Card{ID: 1, Title: "Fix login"}
+	longComment := strings.Repeat("é", maxChatExcerpt+10)
+	tests := []struct {
+		event string
+		data  interface{}
+		want  string
+	}{
+		{models.WebhookEventCardUpdated, map[string]interface{}{"card": webhookCard(card)}, `"Fix login" was updated`},
+		{models.WebhookEventCardUpdated, map[string]interface{}{"card": webhookCard(&models.Card{Title: "Fix login", CompletedAt: &now})}, `"Fix login" was completed`},
+		{models.WebhookEventCardUpdated, map[string]interface{}{"card": webhookCard(&models.Card{Title: "Fix login", CompletedAt: &now, ArchivedAt: &now})}, `"Fix login" was archived`},
+		{models.WebhookEventCommentAdded, map[string]interface{}{"card_title": "Fix login", "comment": map[string]interface{}{"comment": "On it", "user": map[string]string{"fullname": "Ana"}}}, `Ana commented on "Fix login": On it`},
+		{models.WebhookEventCommentAdded, map[string]interface{}{"card_title": "Fix login", "comment": map[string]interface{}{"comment": longComment, "user": map[string]string{"fullname": "Ana"}}}, `Ana commented on "Fix login": ` + longComment[:2*maxChatExcerpt] + "…"},
+		{models.WebhookEventMemberShared, map[string]interface{}{"user": map[string]string{"fullname": "Budi"}, "role": "editor"}, "Budi joined the workspace as editor"},
+		{models.WebhookEventPing, nil, "Test message from KelarIn: this webhook is set up correctly"},
+	}
+	for _, tt := range tests {
+		got, err := FormatChatMessage(tt.event, tt.data)
+		if err != nil || got != tt.want {
+			t.Errorf("FormatChatMessage(%s) = %q, %v, want %q", tt.event, got, err, tt.want)
+		}
+	}
+
+	if _, err := FormatChatMessage(models.WebhookEventCardCreated, map[string]interface{}{}); err == nil {
+		t.Error("FormatChatMessage() without a card = nil error, want an error")
+	}
+}
+
+func TestParseChatCardText(t *testing.T) {
+	tests := []struct {
+		text, title, description string
+	}{
+		{"Fix login | Users see a blank page", "Fix login", "Users see a blank page"},
+		{"  Fix login  ", "Fix login", ""},
+		{"Fix login | a | b", "Fix login", "a | b"},
+		{" | no title", "", "no title"},
+	}
+	for _, tt := range tests {
+		if title, description := ParseChatCardText(tt.text); title != tt.title || description != tt.description {
+			t.Errorf("ParseChatCardText(%q) = %q, %q, want %q, %q", tt.text, title, description, tt.title, tt.description)
+		}
+	}
+}
+
+func TestValidateChatSigningKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		provider, key string
+		valid         bool
+	}{
+		{models.ChatProviderSlack, "secret", true},
+		{models.ChatProviderSlack, "", false},
+		{models.ChatProviderDiscord, hex.EncodeToString(publicKey), true},
+		{models.ChatProviderDiscord, "secret", false},
+		{models.ChatProviderDiscord, hex.EncodeToString(publicKey[:16]), false},
+		{"teams", "secret", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateChatSigningKey(tt.provider, tt.key); (err == nil) != tt.valid {
+			t.Errorf("ValidateChatSigningKey(%q, %q) = %v, want valid %t", tt.provider, tt.key, err, tt.valid)
+		}
+	}
+}
+
+func TestChatWebhookDelivery(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+
+	for _, format := range []string{models.WebhookFormatSlack, models.WebhookFormatDiscord} {
+		var received map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				t.Errorf("%s: body is not a JSON object: %v", format, err)
+			}
+		}))
+
+		payload, err := newWebhookPayload(1, models.WebhookEventCardUpdated, map[string]interface{}{
+			"card": webhookCard(&models.Card{ID: 1, Title: "Fix login"}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := webhookBody(format, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		webhook := &models.Webhook{URL: server.URL, Secret: "secret", Format: format, Enabled: true}
+		delivery := &models.WebhookDelivery{Event: payload.Event, Payload: string(body), Status: models.WebhookDeliveryPending}
+		sendWebhookAttempt(delivery, webhook, WebhookMaxAttempts, time.Now())
+		server.Close()
+
+		if delivery.Status != models.WebhookDeliverySucceeded {
+			t.Errorf("%s: Status = %q (error %q), want succeeded", format, delivery.Status, delivery.Error)
+		}
+		field := "text"
+		if format == models.WebhookFormatDiscord {
+			field = "content"
+		}
+		if want := `"Fix login" was updated`; received[field] != want || len(received) != 1 {
+			t.Errorf("%s: received %v, want {%q: %q}", format, received, field, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"time"
+
+	"kelarin-backend/models"
+)
+
+// CompletionPercent returns the share of done subtasks, rounded down, or nil if there are none.
+func CompletionPercent(subtasks []models.Subtask) *int {
+	if len(subtasks) == 0 {
+		return nil
+	}
+	done := 0
+	for _, subtask := range subtasks {
+		if subtask.IsDone {
+			done++
+		}
+	}
+	percent := done * 100 / len(subtasks)
+	return &percent
+}
+
+// SetCompletionPercents sets the completion percentage of cards loaded with their subtasks.
+func SetCompletionPercents(cards []models.Card) {
+	for i := range cards {
+		cards[i].CompletionPercent = CompletionPercent(cards[i].Subtasks)
+	}
+}
+
+// NewCardFromSubtask builds the card a checklist item is converted into: a card in the same
+// list with the item's title, its due date as deadline and its assignee, and a relates_to link
+// from the original card to it.
+func NewCardFromSubtask(subtask *models.Subtask, parent *models.Card, actorID uint, now time.Time) (*models.Card, *models.CardLink) {
+	card := &models.Card{
+		Title:     subtask.Title,
+		ListID:    parent.ListID,
+		Deadline:  subtask.DueDate,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if subtask.IsDone {
+		card.CompletedAt = &now
+	}
+	if subtask.AssigneeID != nil {
+		card.Assignees = []models.CardAssignee{{UserID: *subtask.AssigneeID}}
+	}
+
+	link := &models.CardLink{
+		CardID:      parent.ID,
+		Type:        models.CardLinkRelatesTo,
+		CreatedByID: actorID,
+		CreatedAt:   now,
+	}
+	return card, link
+}
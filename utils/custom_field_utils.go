@@ -0,0 +1,296 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// Custom field limits.
+const (
+	MaxCustomFieldNameLength   = 100
+	MaxCustomFieldOptions      = 50
+	MaxCustomFieldOptionLength = 100
+	MaxCustomFieldTextLength   = 1000
+)
+
+// IsValidCustomFieldType reports whether fieldType is a supported custom field type.
+func IsValidCustomFieldType(fieldType string) bool {
+	switch fieldType {
+	case models.CustomFieldText, models.CustomFieldNumber, models.CustomFieldDate, models.CustomFieldSelect,
+		models.CustomFieldMultiSelect, models.CustomFieldCheckbox, models.CustomFieldUser:
+		return true
+	}
+	return false
+}
+
+// isSelectField reports whether a field type takes its values from the field's options.
+func isSelectField(fieldType string) bool {
+	return fieldType == models.CustomFieldSelect || fieldType == models.CustomFieldMultiSelect
+}
+
+// ValidateCustomField checks a custom field's name, type and options. Select and multi_select
+// fields need between 1 and MaxCustomFieldOptions distinct options; other types take none.
+func ValidateCustomField(field *models.CustomField) error {
+	field.Name = strings.TrimSpace(field.Name)
+	if field.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := ValidateLength("Name", field.Name, MaxCustomFieldNameLength); err != nil {
+		return err
+	}
+	if !IsValidCustomFieldType(field.Type) {
+		return errors.New("type must be text, number, date, select, multi_select, checkbox or user")
+	}
+
+	if !isSelectField(field.Type) {
+		if len(field.Options) > 0 {
+			return errors.New("options can only be used with select and multi_select fields")
+		}
+		field.Options = models.CustomFieldOptions{}
+		return nil
+	}
+
+	if len(field.Options) == 0 || len(field.Options) > MaxCustomFieldOptions {
+		return fmt.Errorf("a %s field must have between 1 and %d options", field.Type, MaxCustomFieldOptions)
+	}
+	seen := make(map[string]bool, len(field.Options))
+	for i, option := range field.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return errors.New("options cannot be empty")
+		}
+		if err := ValidateLength("Option", option, MaxCustomFieldOptionLength); err != nil {
+			return err
+		}
+		if seen[option] {
+			return fmt.Errorf("option %q is listed twice", option)
+		}
+		seen[option] = true
+		field.Options[i] = option
+	}
+	return nil
+}
+
+// ParseCustomFieldOptions reads select options sent as a JSON array or a comma-separated list.
+func ParseCustomFieldOptions(raw string) (models.CustomFieldOptions, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return models.CustomFieldOptions{}, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var options models.CustomFieldOptions
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			return nil, errors.New("options must be a JSON array of strings")
+		}
+		return options, nil
+	}
+
+	options := models.CustomFieldOptions{}
+	for _, option := range strings.Split(raw, ",") {
+		options = append(options, strings.TrimSpace(option))
+	}
+	return options, nil
+}
+
+// NormalizeCustomFieldValue validates a value sent for a custom field and returns it in its
+// canonical JSON form:
+//   - text: any text up to MaxCustomFieldTextLength characters
+//   - number: a finite number
+//   - date: an RFC3339 time or a YYYY-MM-DD date, stored as RFC3339 in UTC
+//   - select: one of the field's options
+//   - multi_select: options as a JSON array or comma-separated, stored without duplicates
+//   - checkbox: true or false
+//   - user: the ID of a member of the field's workspace
+func NormalizeCustomFieldValue(field *models.CustomField, raw string) (models.JSONValue, error) {
+	var value interface{}
+	switch field.Type {
+	case models.CustomFieldText:
+		if err := ValidateLength("Value", raw, MaxCustomFieldTextLength); err != nil {
+			return "", err
+		}
+		value = raw
+
+	case models.CustomFieldNumber:
+		number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+			return "", fmt.Errorf("%s must be a number", field.Name)
+		}
+		value = number
+
+	case models.CustomFieldDate:
+		date, err := parseCustomFieldDate(raw)
+		if err != nil {
+			return "", fmt.Errorf("%s must be an RFC3339 time or a YYYY-MM-DD date", field.Name)
+		}
+		value = date.UTC().Format(time.RFC3339)
+
+	case models.CustomFieldSelect:
+		option := strings.TrimSpace(raw)
+		if !hasOption(field, option) {
+			return "", fmt.Errorf("%s must be one of its options", field.Name)
+		}
+		value = option
+
+	case models.CustomFieldMultiSelect:
+		options, err := ParseCustomFieldOptions(raw)
+		if err != nil {
+			return "", err
+		}
+		selected := make([]string, 0, len(options))
+		seen := make(map[string]bool, len(options))
+		for _, option := range options {
+			option = strings.TrimSpace(option)
+			if !hasOption(field, option) {
+				return "", fmt.Errorf("%q is not an option of %s", option, field.Name)
+			}
+			if !seen[option] {
+				seen[option] = true
+				selected = append(selected, option)
+			}
+		}
+		value = selected
+
+	case models.CustomFieldCheckbox:
+		checked, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", field.Name)
+		}
+		value = checked
+
+	case models.CustomFieldUser:
+		userID, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s must be a user ID", field.Name)
+		}
+		if _, err := CheckRoleInWorkspace(uint(userID), field.WorkspaceID); err != nil {
+			return "", fmt.Errorf("%s must be a member of the workspace", field.Name)
+		}
+		value = userID
+
+	default:
+		return "", fmt.Errorf("unknown custom field type %q", field.Type)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return models.JSONValue(data), nil
+}
+
+// NewCustomFieldFilter builds a card search filter on a custom field. op is one of the
+// repositories.CustomFieldOp constants; min and max only apply to number and date fields.
+func NewCustomFieldFilter(field *models.CustomField, op, raw string) (repositories.CustomFieldFilter, error) {
+	filter := repositories.CustomFieldFilter{FieldID: field.ID, Type: field.Type, Op: op}
+	switch op {
+	case repositories.CustomFieldOpEquals:
+		if field.Type == models.CustomFieldMultiSelect && strings.Contains(raw, ",") {
+			return filter, errors.New("a multi_select filter takes a single option")
+		}
+		value, err := NormalizeCustomFieldValue(field, raw)
+		if err != nil {
+			return filter, err
+		}
+		filter.Value = string(value)
+
+	case repositories.CustomFieldOpMin, repositories.CustomFieldOpMax:
+		switch field.Type {
+		case models.CustomFieldNumber:
+			number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+				return filter, fmt.Errorf("%s %s must be a number", field.Name, op)
+			}
+			filter.Value = strconv.FormatFloat(number, 'f', -1, 64)
+		case models.CustomFieldDate:
+			date, err := parseCustomFieldDate(raw)
+			if err != nil {
+				return filter, fmt.Errorf("%s %s must be an RFC3339 time or a YYYY-MM-DD date", field.Name, op)
+			}
+			filter.Value = date.UTC().Format(time.RFC3339)
+		default:
+			return filter, fmt.Errorf("%s filters only apply to number and date fields", op)
+		}
+
+	case repositories.CustomFieldOpSet:
+		set, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, errors.New("set must be true or false")
+		}
+		if !set {
+			filter.Op = repositories.CustomFieldOpUnset
+		}
+
+	default:
+		return filter, fmt.Errorf("unknown custom field filter %q", op)
+	}
+	return filter, nil
+}
+
+// PruneCustomFieldValues brings the stored values of a select or multi_select field in line
+// with its options after they have changed: values that are no longer options are removed.
+func PruneCustomFieldValues(field *models.CustomField) error {
+	if !isSelectField(field.Type) {
+		return nil
+	}
+
+	var values []models.CardCustomFieldValue
+	if err := repositories.GetCustomFieldValuesByField(field.ID, &values); err != nil {
+		return err
+	}
+	for _, value := range values {
+		var err error
+		if field.Type == models.CustomFieldSelect {
+			var option string
+			if json.Unmarshal([]byte(value.Value), &option) != nil || !hasOption(field, option) {
+				err = repositories.DeleteCardCustomFieldValueByID(value.ID)
+			}
+		} else {
+			var selected []string
+			_ = json.Unmarshal([]byte(value.Value), &selected)
+			kept := make([]string, 0, len(selected))
+			for _, option := range selected {
+				if hasOption(field, option) {
+					kept = append(kept, option)
+				}
+			}
+			switch {
+			case len(kept) == 0:
+				err = repositories.DeleteCardCustomFieldValueByID(value.ID)
+			case len(kept) < len(selected):
+				data, _ := json.Marshal(kept)
+				err = repositories.UpdateCardCustomFieldValue(value.ID, models.JSONValue(data))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasOption reports whether option is one of a field's options.
+func hasOption(field *models.CustomField, option string) bool {
+	for _, o := range field.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCustomFieldDate parses an RFC3339 time or a YYYY-MM-DD date (midnight UTC).
+func parseCustomFieldDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if date, err := time.Parse(time.RFC3339, raw); err == nil {
+		return date, nil
+	}
+	return time.Parse("2006-01-02", raw)
+}
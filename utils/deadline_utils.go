@@ -0,0 +1,49 @@
+package utils
+
+import "time"
+
+// Deadline buckets, relative to the current day and week in the user's time zone.
+const (
+	DeadlineBucketOverdue    = "overdue"
+	DeadlineBucketToday      = "today"
+	DeadlineBucketThisWeek   = "this_week"
+	DeadlineBucketLater      = "later"
+	DeadlineBucketNoDeadline = "no_deadline"
+)
+
+// DeadlineBuckets lists every deadline bucket in display order.
+var DeadlineBuckets = []string{
+	DeadlineBucketOverdue,
+	DeadlineBucketToday,
+	DeadlineBucketThisWeek,
+	DeadlineBucketLater,
+	DeadlineBucketNoDeadline,
+}
+
+// DeadlineBucket returns the bucket of a deadline: overdue if it has passed, today if it is
+// before midnight, this week if it is before the end of Sunday, and later otherwise.
+func DeadlineBucket(deadline *time.Time, now time.Time) string {
+	if deadline == nil {
+		return DeadlineBucketNoDeadline
+	}
+	if deadline.Before(now) {
+		return DeadlineBucketOverdue
+	}
+
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfToday := startOfToday.AddDate(0, 0, 1)
+	if deadline.Before(endOfToday) {
+		return DeadlineBucketToday
+	}
+
+	// Weeks run Monday to Sunday.
+	daysUntilMonday := (8 - int(now.Weekday())) % 7
+	if daysUntilMonday == 0 {
+		daysUntilMonday = 7
+	}
+	endOfWeek := startOfToday.AddDate(0, 0, daysUntilMonday)
+	if deadline.Before(endOfWeek) {
+		return DeadlineBucketThisWeek
+	}
+	return DeadlineBucketLater
+}
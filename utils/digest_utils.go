@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/mailer"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// DigestData is the data rendered into the email digest template.
+type DigestData struct {
+	User     models.User
+	Period   string
+	Assigned []models.Card
+	Upcoming []models.Card
+	Overdue  []models.Card
+	Comments []models.CardComment
+}
+
+// DigestPeriod returns how often a digest of the given frequency is sent.
+func DigestPeriod(frequency string) (time.Duration, error) {
+	switch frequency {
+	case models.DigestFrequencyDaily:
+		return 24 * time.Hour, nil
+	case models.DigestFrequencyWeekly:
+		return 7 * 24 * time.Hour, nil
+	}
+	return 0, errors.New("digest frequency must be daily or weekly")
+}
+
+// BuildDigest collects the cards, deadlines and comments to include in a user's digest.
+// Upcoming deadlines cover the next period; comments cover everything since the given time.
+func BuildDigest(user *models.User, frequency string, since, now time.Time) (*DigestData, error) {
+	period, err := DigestPeriod(frequency)
+	if err != nil {
+		return nil, err
+	}
+
+	var assigned []models.Card
+	if err := repositories.GetCardsAssignedToUser(user.ID, &assigned); err != nil {
+		return nil, err
+	}
+
+	data := &DigestData{
+		User:     *user,
+		Period:   frequency,
+		Assigned: assigned,
+	}
+	for _, card := range assigned {
+		if card.Deadline == nil {
+			continue
+		}
+		if !card.Deadline.After(now) {
+			data.Overdue = append(data.Overdue, card)
+		} else if card.Deadline.Before(now.Add(period)) {
+			data.Upcoming = append(data.Upcoming, card)
+		}
+	}
+
+	if err := repositories.GetCommentsOnAssignedCardsSince(user.ID, since, &data.Comments); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// RenderDigest renders a digest as an email message.
+func RenderDigest(data *DigestData) (mailer.Message, error) {
+	html, err := mailer.Render("digest.html", data)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.Message{
+		To:      data.User.Email,
+		Subject: fmt.Sprintf("Your KelarIn %s digest", data.Period),
+		HTML:    html,
+	}, nil
+}
+
+// SendEmailDigest builds, renders and sends a user's digest and records when it was sent.
+func SendEmailDigest(m mailer.Mailer, setting *models.EmailDigestSetting, now time.Time) error {
+	period, err := DigestPeriod(setting.Frequency)
+	if err != nil {
+		return err
+	}
+
+	since := now.Add(-period)
+	if setting.LastSentAt != nil && setting.LastSentAt.After(since) {
+		since = *setting.LastSentAt
+	}
+
+	data, err := BuildDigest(&setting.User, setting.Frequency, since, now)
+	if err != nil {
+		return err
+	}
+
+	msg, err := RenderDigest(data)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Send(msg); err != nil {
+		return err
+	}
+
+	return repositories.MarkEmailDigestSent(setting.UserID, now)
+}
+
+// StartEmailDigestScheduler checks every DIGEST_CHECK_INTERVAL (default 1h) for users whose
+// daily or weekly digest is due and sends it through the given mailer.
+func StartEmailDigestScheduler(m mailer.Mailer) {
+	interval := env.Duration("DIGEST_CHECK_INTERVAL", time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sendDueEmailDigests(m, time.Now(), interval)
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Email digest scheduler started (interval %s)", interval)
+}
+
+// sendDueEmailDigests sends every digest whose period has elapsed since it was last sent.
+// The check interval is used as tolerance so that digests do not drift later on every run.
+func sendDueEmailDigests(m mailer.Mailer, now time.Time, interval time.Duration) {
+	var settings []models.EmailDigestSetting
+	if err := repositories.GetActiveEmailDigestSettings(&settings); err != nil {
+		log.Println("Error fetching email digest settings:", err)
+		return
+	}
+
+	for _, setting := range settings {
+		period, err := DigestPeriod(setting.Frequency)
+		if err != nil {
+			continue
+		}
+		if setting.LastSentAt != nil && now.Sub(*setting.LastSentAt) < period-interval {
+			continue
+		}
+
+		if err := SendEmailDigest(m, &setting, now); err != nil {
+			log.Printf("Error sending %s digest to user %d: %v", setting.Frequency, setting.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag returns the entity tag of a row version.
+func ETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// IfMatchSatisfied reports whether an If-Match header value allows updating a row at the given
+// version. An empty header or "*" always matches. Tags are compared strongly, as RFC 9110
+// requires for If-Match, so a weak tag never matches.
+func IfMatchSatisfied(header string, version uint) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return true
+	}
+
+	current := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == current {
+			return true
+		}
+	}
+	return false
+}
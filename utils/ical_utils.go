@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kelarin-backend/models"
+)
+
+// icalTimeFormat is the iCalendar UTC date-time format.
+const icalTimeFormat = "20060102T150405Z"
+
+// GenerateCalendarToken returns a new random token for an iCalendar subscription URL.
+func GenerateCalendarToken() (string, error) {
+	return GenerateSecureToken(32)
+}
+
+// BuildICalendar renders cards with a deadline as an iCalendar (RFC 5545) feed.
+// Each card becomes an event at its deadline; the list and workspace are added as context.
+func BuildICalendar(name string, cards []models.Card, now time.Time) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//KelarIn//Kanban Deadlines//EN")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(name))
+	writeICalLine(&b, "X-PUBLISHED-TTL:PT1H")
+
+	stamp := now.UTC().Format(icalTimeFormat)
+	for _, card := range cards {
+		if card.Deadline == nil {
+			continue
+		}
+
+		description := card.Description
+		if card.List.Title != "" {
+			description = fmt.Sprintf("%s / %s\n\n%s", card.List.Workspace.Title, card.List.Title, card.Description)
+		}
+
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, fmt.Sprintf("UID:card-%d@kelarin", card.ID))
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "LAST-MODIFIED:"+card.UpdatedAt.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DTSTART:"+card.Deadline.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(card.Title))
+		writeICalLine(&b, "DESCRIPTION:"+escapeICalText(strings.TrimSpace(description)))
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICalText escapes a value for an iCalendar TEXT property.
+func escapeICalText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeICalLine writes a content line, folding it into lines of at most 75 octets
+// without splitting multi-byte characters.
+func writeICalLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards the limit.
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// isRuneStart reports whether the byte starts a UTF-8 encoded character.
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"errors"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kelarin-backend/env"
+	"kelarin-backend/inbound"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// MaxInboundAttachmentSize is the largest email attachment that is stored on a card; larger
+// attachments are dropped.
+const MaxInboundAttachmentSize = 10 << 20
+
+// ErrNoInboundList is returned when none of the recipients of an inbound email is the inbound
+// address of an active list that can take new cards.
+var ErrNoInboundList = errors.New("no recipient matches a list inbound address")
+
+// forwardPrefixPattern matches the "Fwd:" style prefixes mail clients add to forwarded subjects.
+var forwardPrefixPattern = regexp.MustCompile(`(?i)^\s*(fwd?|fw)\s*:\s*`)
+
+// unsafeFileNameChars matches characters that are replaced in stored attachment file names.
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// htmlStripPolicy removes all markup from HTML email bodies.
+var htmlStripPolicy = bluemonday.StrictPolicy()
+
+// GenerateInboundToken returns a new random token for a list inbound address. It is lowercase
+// so it survives mail systems that change the case of addresses.
+func GenerateInboundToken() (string, error) {
+	return GenerateSecureToken(12)
+}
+
+// InboundEmailDomain returns the domain inbound addresses are issued under (INBOUND_EMAIL_DOMAIN).
+func InboundEmailDomain() string {
+	return env.Get("INBOUND_EMAIL_DOMAIN", "inbound.kelarin.local")
+}
+
+// InboundAddress returns the email address of an inbound address token.
+func InboundAddress(token string) string {
+	return token + "@" + InboundEmailDomain()
+}
+
+// ProcessInboundEmail creates a card in every list the email is addressed to, with the subject
+// as title, the body as description and the attachments as card attachments. Recipients are
+// matched on the local part of the address only, so the email may reach the backend through
+// any domain routed to the inbound-mail provider.
+func ProcessInboundEmail(email *inbound.Email) ([]models.Card, error) {
+	var tokens []string
+	for _, recipient := range email.To {
+		at := strings.LastIndex(recipient, "@")
+		if at <= 0 {
+			continue
+		}
+		tokens = append(tokens, strings.ToLower(recipient[:at]))
+	}
+	if len(tokens) == 0 {
+		return nil, ErrNoInboundList
+	}
+
+	var addresses []models.ListInboundAddress
+	if err := repositories.GetInboundAddressesByTokens(tokens, &addresses); err != nil {
+		return nil, err
+	}
+
+	title := inboundCardTitle(email.Subject)
+	description := inboundCardDescription(email)
+
+	cards := make([]models.Card, 0, len(addresses))
+	for _, address := range addresses {
+		// Trashed lists are not preloaded; archived lists do not take new cards.
+		if address.List.ID == 0 || address.List.ArchivedAt != nil {
+			continue
+		}
+		// Lists at a hard WIP limit turn the email away like a list that does not exist.
+		if _, err := CheckWIPLimit(address.ListID, 1); errors.Is(err, ErrWIPLimitReached) {
+			continue
+		} else if err != nil {
+			return cards, err
+		}
+
+		now := time.Now()
+		card := models.Card{
+			Title:       title,
+			Description: description,
+			ListID:      address.ListID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := repositories.CreateCard(&card); errors.Is(err, ErrWIPLimitReached) {
+			continue
+		} else if err != nil {
+			return cards, err
+		}
+		saveInboundAttachments(card.ID, email.Attachments)
+
+		EmitCardWebhookEvent(models.WebhookEventCardCreated, &card)
+		DispatchAutomationEvent(AutomationEvent{Trigger: models.AutomationTriggerCardCreated, CardID: card.ID, ListID: card.ListID})
+		cards = append(cards, card)
+	}
+
+	if len(cards) == 0 {
+		return nil, ErrNoInboundList
+	}
+	return cards, nil
+}
+
+// inboundCardTitle turns an email subject into a card title.
+func inboundCardTitle(subject string) string {
+	for {
+		stripped := forwardPrefixPattern.ReplaceAllString(subject, "")
+		if stripped == subject {
+			break
+		}
+		subject = stripped
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	return truncateRunes(subject, MaxCardTitleLength)
+}
+
+// inboundCardDescription returns the plain-text body of an email, falling back to its HTML body
+// with the markup removed.
+func inboundCardDescription(email *inbound.Email) string {
+	body := email.Text
+	if strings.TrimSpace(body) == "" && email.HTML != "" {
+		body = html.UnescapeString(htmlStripPolicy.Sanitize(email.HTML))
+	}
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	return truncateRunes(strings.TrimSpace(body), MaxCardDescriptionLength)
+}
+
+// inboundAttachmentDir is the directory the email attachments of a card are stored in.
+func inboundAttachmentDir(cardID uint) string {
+	return filepath.Join(".", "uploads", "inbound", strconv.FormatUint(uint64(cardID), 10))
+}
+
+// InboundAttachmentPath returns the file an email attachment of a card is stored in, or false
+// if name is not the name of a stored attachment file.
+func InboundAttachmentPath(cardID uint, name string) (string, bool) {
+	if name == "" || unsafeFileNameChars.MatchString(name) || strings.Trim(name, "._") != name {
+		return "", false
+	}
+	return filepath.Join(inboundAttachmentDir(cardID), name), true
+}
+
+// inboundAttachmentURL returns the authenticated download URL of an email attachment of a card.
+func inboundAttachmentURL(cardID uint, name string) string {
+	return "/api/kanban/cards/" + strconv.FormatUint(uint64(cardID), 10) + "/inbound-attachments/" + name
+}
+
+// saveInboundAttachments stores the attachments of an email under uploads/inbound/<card ID>
+// and adds them to the card with their download URL. Failures are logged so one bad attachment
+// does not lose the card.
+func saveInboundAttachments(cardID uint, attachments []inbound.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+
+	dir := inboundAttachmentDir(cardID)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		log.Println("Error creating inbound attachment directory:", err)
+		return
+	}
+
+	used := make(map[string]bool, len(attachments))
+	for i, attachment := range attachments {
+		if len(attachment.Data) > MaxInboundAttachmentSize {
+			log.Printf("Skipping inbound attachment %q of card %d: too large (%d bytes)", attachment.FileName, cardID, len(attachment.Data))
+			continue
+		}
+
+		name := unsafeFileNameChars.ReplaceAllString(filepath.Base(attachment.FileName), "_")
+		name = strings.Trim(name, "._")
+		if name == "" {
+			name = "attachment"
+		}
+		if used[name] {
+			name = strconv.Itoa(i+1) + "_" + name
+		}
+		used[name] = true
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, attachment.Data, 0o644); err != nil {
+			log.Println("Error saving inbound attachment:", err)
+			continue
+		}
+
+		fileName := attachment.FileName
+		if fileName == "" {
+			fileName = name
+		}
+		row := models.CardAttachment{
+			CardID:    cardID,
+			URL:       inboundAttachmentURL(cardID, name),
+			FileName:  fileName,
+			CreatedAt: time.Now(),
+		}
+		if err := repositories.CreateCardAttachment(&row); err != nil {
+			log.Println("Error creating inbound card attachment:", err)
+		}
+	}
+}
+
+// truncateRunes shortens s to at most max characters.
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max]))
+}
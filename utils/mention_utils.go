@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// mentionPattern matches "@handle" tokens, where the handle is an email address or its local part.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// MentionSpan is a resolved "@handle" in a piece of text.
+// Start and End are UTF-16 offsets of the span including the "@".
+type MentionSpan struct {
+	User   models.User
+	Handle string
+	Start  int
+	End    int
+}
+
+// ParseMentions finds every "@handle" in text that refers to a member of the workspace.
+// A member is mentioned by "@" followed by their email or the part of it before the "@";
+// handles that match no member, or a local part shared by several members, are ignored.
+func ParseMentions(workspaceID uint, text string) ([]MentionSpan, error) {
+	matches := mentionPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var members []models.User
+	if err := repositories.GetWorkspaceMembers(workspaceID, &members); err != nil {
+		return nil, err
+	}
+
+	var spans []MentionSpan
+	for _, match := range matches {
+		handleStart, handleEnd := match[2], match[3]
+		// A trailing period ends the sentence rather than the handle.
+		for handleEnd > handleStart && text[handleEnd-1] == '.' {
+			handleEnd--
+		}
+		handle := text[handleStart:handleEnd]
+
+		user, ok := findMentionedMember(members, strings.ToLower(handle))
+		if !ok {
+			continue
+		}
+
+		start := utf16Len(text[:handleStart-1])
+		spans = append(spans, MentionSpan{
+			User:   user,
+			Handle: handle,
+			Start:  start,
+			End:    start + utf16Len(text[handleStart-1:handleEnd]),
+		})
+	}
+	return spans, nil
+}
+
+// MentionedUserIDs returns the distinct IDs of the users mentioned in the spans.
+func MentionedUserIDs(spans []MentionSpan) []uint {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, span := range spans {
+		if !seen[span.User.ID] {
+			seen[span.User.ID] = true
+			ids = append(ids, span.User.ID)
+		}
+	}
+	return ids
+}
+
+// MentionHandle returns the handle to mention a user with: the local part of their email,
+// or the full email when another member shares the same local part.
+func MentionHandle(user *models.User, members []models.User) string {
+	local, _, _ := strings.Cut(user.Email, "@")
+	for _, member := range members {
+		if member.ID == user.ID {
+			continue
+		}
+		memberLocal, _, _ := strings.Cut(member.Email, "@")
+		if strings.EqualFold(local, memberLocal) {
+			return user.Email
+		}
+	}
+	return local
+}
+
+// findMentionedMember returns the member a lower-case handle refers to, preferring an exact
+// email match. A local part shared by several members is ambiguous and matches no one; those
+// members are mentioned by their full email, as MentionHandle suggests.
+func findMentionedMember(members []models.User, handle string) (models.User, bool) {
+	for _, member := range members {
+		if strings.ToLower(member.Email) == handle {
+			return member, true
+		}
+	}
+
+	var found models.User
+	matches := 0
+	for _, member := range members {
+		local, _, _ := strings.Cut(strings.ToLower(member.Email), "@")
+		if local == handle {
+			found = member
+			matches++
+		}
+	}
+	return found, matches == 1
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// Notify stores a notification for its recipient, unless the recipient caused the event
+// themselves or has disabled notifications of that type.
+func Notify(notification *models.Notification) error {
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return nil
+	}
+
+	enabled, err := repositories.IsNotificationEnabled(notification.UserID, notification.Type)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	return repositories.CreateNotification(notification)
+}
+
+// NotifyCardAssigned notifies a user that they have been assigned to a card.
+func NotifyCardAssigned(card *models.Card, userID, actorID uint) error {
+	workspaceID := card.List.WorkspaceID
+	cardID := card.ID
+
+	return Notify(&models.Notification{
+		UserID:      userID,
+		Type:        models.NotificationTypeCardAssigned,
+		Title:       "Assigned to card",
+		Message:     fmt.Sprintf("You have been assigned to %q", card.Title),
+		WorkspaceID: &workspaceID,
+		CardID:      &cardID,
+		ActorID:     &actorID,
+	})
+}
+
+// NotifyCommentMention notifies a user that they have been mentioned in a comment on a card.
+func NotifyCommentMention(card *models.Card, userID, actorID uint) error {
+	workspaceID := card.List.WorkspaceID
+	cardID := card.ID
+
+	return Notify(&models.Notification{
+		UserID:      userID,
+		Type:        models.NotificationTypeCommentMention,
+		Title:       "Mentioned in a comment",
+		Message:     fmt.Sprintf("You were mentioned in a comment on %q", card.Title),
+		WorkspaceID: &workspaceID,
+		CardID:      &cardID,
+		ActorID:     &actorID,
+	})
+}
+
+// NotifyWorkspaceShared notifies a user that they have been added to a workspace.
+func NotifyWorkspaceShared(workspace *models.Workspace, userID uint, role string, actorID uint) error {
+	workspaceID := workspace.ID
+
+	return Notify(&models.Notification{
+		UserID:      userID,
+		Type:        models.NotificationTypeWorkspaceShared,
+		Title:       "Added to workspace",
+		Message:     fmt.Sprintf("You have been added to %q as %s", workspace.Title, role),
+		WorkspaceID: &workspaceID,
+		ActorID:     &actorID,
+	})
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"time"
+
+	"kelarin-backend/models"
+)
+
+// IsValidRecurrence reports whether rule is a supported recurrence rule. An empty rule means
+// the card does not repeat.
+func IsValidRecurrence(rule string) bool {
+	switch rule {
+	case "", models.RecurrenceDaily, models.RecurrenceWeekly, models.RecurrenceMonthly:
+		return true
+	}
+	return false
+}
+
+// NextRecurrence returns t shifted forward by interval periods of the rule. Monthly shifts are
+// clamped to the last day of the target month, so the 31st repeats on the 30th in April.
+func NextRecurrence(t time.Time, rule string, interval int) time.Time {
+	if interval < 1 {
+		interval = 1
+	}
+
+	switch rule {
+	case models.RecurrenceDaily:
+		return t.AddDate(0, 0, interval)
+	case models.RecurrenceWeekly:
+		return t.AddDate(0, 0, 7*interval)
+	case models.RecurrenceMonthly:
+		firstOfTarget := time.Date(t.Year(), t.Month()+time.Month(interval), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		lastDay := firstOfTarget.AddDate(0, 1, -1).Day()
+		day := t.Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return firstOfTarget.AddDate(0, 0, day-1)
+	}
+	return t
+}
+
+// NewNextOccurrence builds the next occurrence of a recurring card: a copy of its title,
+// description, list, priority and rule with the start date and deadline shifted by one
+// recurrence. Its checklists and subtasks are copied as not done, along with its assignees,
+// labels and custom field values. It returns nil if the card does not repeat, or already has a
+// next occurrence from an earlier completion.
+func NewNextOccurrence(card *models.Card, now time.Time) *models.Card {
+	if card.Recurrence == "" || card.NextOccurrenceID != nil {
+		return nil
+	}
+
+	next := &models.Card{
+		Title:              card.Title,
+		Description:        card.Description,
+		ListID:             card.ListID,
+		Priority:           card.Priority,
+		Recurrence:         card.Recurrence,
+		RecurrenceInterval: card.RecurrenceInterval,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if card.StartDate != nil {
+		start := NextRecurrence(*card.StartDate, card.Recurrence, card.RecurrenceInterval)
+		next.StartDate = &start
+	}
+	if card.Deadline != nil {
+		deadline := NextRecurrence(*card.Deadline, card.Recurrence, card.RecurrenceInterval)
+		next.Deadline = &deadline
+	}
+
+	for _, checklist := range card.Checklists {
+		copied := models.Checklist{Title: checklist.Title, Position: checklist.Position, CreatedAt: now, UpdatedAt: now}
+		for _, item := range checklist.Items {
+			copied.Items = append(copied.Items, nextOccurrenceSubtask(card, item))
+		}
+		next.Checklists = append(next.Checklists, copied)
+	}
+	for _, subtask := range card.Subtasks {
+		if subtask.ChecklistID == nil {
+			next.Subtasks = append(next.Subtasks, nextOccurrenceSubtask(card, subtask))
+		}
+	}
+	for _, assignee := range card.Assignees {
+		next.Assignees = append(next.Assignees, models.CardAssignee{UserID: assignee.UserID})
+	}
+	for _, label := range card.Labels {
+		next.Labels = append(next.Labels, models.CardLabel{LabelID: label.LabelID, CreatedAt: now})
+	}
+	for _, value := range card.CustomFields {
+		next.CustomFields = append(next.CustomFields, models.CardCustomFieldValue{FieldID: value.FieldID, Value: value.Value})
+	}
+	return next
+}
+
+// nextOccurrenceSubtask copies a subtask of a recurring card for its next occurrence, not done
+// and with its due date moved forward by the recurrence rule.
+func nextOccurrenceSubtask(card *models.Card, subtask models.Subtask) models.Subtask {
+	next := models.Subtask{Title: subtask.Title, Position: subtask.Position, AssigneeID: subtask.AssigneeID}
+	if subtask.DueDate != nil {
+		due := NextRecurrence(*subtask.DueDate, card.Recurrence, card.RecurrenceInterval)
+		next.DueDate = &due
+	}
+	return next
+}
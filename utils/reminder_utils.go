@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// reminderKindOverdue is the reminder kind recorded for overdue notifications.
+const reminderKindOverdue = "overdue"
+
+// ReminderLeadTimes returns the configured reminder lead times, sorted from shortest to longest.
+// They are read from REMINDER_LEAD_TIMES as a comma-separated list of durations (default "24h,1h").
+func ReminderLeadTimes() []time.Duration {
+	raw := env.Get("REMINDER_LEAD_TIMES", "24h,1h")
+
+	var leads []time.Duration
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			log.Printf("Ignoring invalid reminder lead time %q", part)
+			continue
+		}
+		leads = append(leads, d)
+	}
+
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+	return leads
+}
+
+// StartDeadlineReminderScheduler runs the deadline reminder check in the background
+// every REMINDER_INTERVAL (default 5m). Reminders already sent are recorded in the
+// database, so restarting the scheduler never sends duplicates.
+func StartDeadlineReminderScheduler() {
+	interval := env.Duration("REMINDER_INTERVAL", 5*time.Minute)
+	leads := ReminderLeadTimes()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := SendDeadlineReminders(time.Now(), leads); err != nil {
+				log.Println("Error sending deadline reminders:", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Deadline reminder scheduler started (interval %s, lead times %v)", interval, leads)
+}
+
+// SendDeadlineReminders notifies the assignees of every card that is within one of the
+// lead times of its deadline, or past it. Only the tightest matching lead time is sent,
+// so a card created an hour before its deadline does not also receive the one-day reminder.
+func SendDeadlineReminders(now time.Time, leads []time.Duration) error {
+	horizon := now
+	if len(leads) > 0 {
+		horizon = now.Add(leads[len(leads)-1])
+	}
+
+	var cards []models.Card
+	if err := repositories.GetCardsWithDeadlineBefore(horizon, &cards); err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		kind, ok := reminderKind(card.Deadline.Sub(now), leads)
+		if !ok {
+			continue
+		}
+
+		for _, assignee := range card.Assignees {
+			if err := sendDeadlineReminder(&card, assignee.UserID, kind); err != nil {
+				log.Printf("Error sending %s reminder for card %d to user %d: %v", kind, card.ID, assignee.UserID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// reminderKind returns the reminder kind for a card whose deadline is the given duration away.
+func reminderKind(remaining time.Duration, leads []time.Duration) (string, bool) {
+	if remaining <= 0 {
+		return reminderKindOverdue, true
+	}
+	for _, lead := range leads {
+		if remaining <= lead {
+			return lead.String(), true
+		}
+	}
+	return "", false
+}
+
+// sendDeadlineReminder creates the reminder notification for a card assignee unless it has already
+// been sent or the assignee has disabled notifications of that type.
+func sendDeadlineReminder(card *models.Card, userID uint, kind string) error {
+	workspaceID := card.List.WorkspaceID
+	cardID := card.ID
+
+	notification := models.Notification{
+		UserID:      userID,
+		WorkspaceID: &workspaceID,
+		CardID:      &cardID,
+		CreatedAt:   time.Now(),
+	}
+	if kind == reminderKindOverdue {
+		notification.Type = models.NotificationTypeCardOverdue
+		notification.Title = "Card overdue"
+		notification.Message = fmt.Sprintf("%q was due %s", card.Title, card.Deadline.UTC().Format(time.RFC1123))
+	} else {
+		notification.Type = models.NotificationTypeDeadlineReminder
+		notification.Title = "Deadline approaching"
+		notification.Message = fmt.Sprintf("%q is due %s", card.Title, card.Deadline.UTC().Format(time.RFC1123))
+	}
+
+	enabled, err := repositories.IsNotificationEnabled(userID, notification.Type)
+	if err != nil || !enabled {
+		return err
+	}
+
+	reminder := models.DeadlineReminder{
+		CardID:    card.ID,
+		UserID:    userID,
+		Kind:      kind,
+		Deadline:  *card.Deadline,
+		CreatedAt: time.Now(),
+	}
+
+	_, err = repositories.CreateDeadlineReminderWithNotification(&reminder, &notification)
+	return err
+}
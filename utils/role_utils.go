@@ -34,3 +34,8 @@ func CheckRoleInWorkspace(userID, workspaceID uint) (string, error) {
 func IsEditorAdminOwner(role string) bool {
 	return role == "editor" || role == "admin" || role == "owner"
 }
+
+// IsAdminOwner returns true if role is "admin" or "owner".
+func IsAdminOwner(role string) bool {
+	return role == "admin" || role == "owner"
+}
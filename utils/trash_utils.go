@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"log"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/repositories"
+)
+
+// TrashRetention returns how long deleted workspaces, lists and cards stay in the trash
+// before they are purged, read from TRASH_RETENTION (default 720h, 30 days).
+func TrashRetention() time.Duration {
+	return env.Duration("TRASH_RETENTION", 30*24*time.Hour)
+}
+
+// StartTrashPurgeScheduler permanently deletes items whose trash retention has passed,
+// checking every TRASH_PURGE_INTERVAL (default 1h).
+func StartTrashPurgeScheduler() {
+	interval := env.Duration("TRASH_PURGE_INTERVAL", time.Hour)
+	retention := TrashRetention()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := repositories.PurgeTrash(time.Now().Add(-retention))
+			if err != nil {
+				log.Println("Error purging trash:", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d items from the trash", purged)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Trash purge scheduler started (interval %s, retention %s)", interval, retention)
+}
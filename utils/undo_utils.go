@@ -0,0 +1,275 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Entities whose operations can be undone.
+const (
+	UndoEntityCard         = "card"
+	UndoEntityList         = "list"
+	UndoEntitySubtask      = "subtask"
+	UndoEntityCardAssignee = "card_assignee"
+	UndoEntityCardLabel    = "card_label"
+)
+
+// Undoable operations.
+const (
+	UndoActionCreate = "create"
+	UndoActionUpdate = "update"
+	UndoActionDelete = "delete"
+)
+
+// Errors returned by Undo.
+var (
+	ErrUndoNotFound = errors.New("undo token not found")
+	ErrUndoExpired  = errors.New("undo window has passed")
+	ErrUndoConflict = errors.New("the item has changed since this operation")
+)
+
+// UndoKey identifies the row affected by an operation: ID for rows with a single primary key,
+// CardID and UserID for card assignees.
+type UndoKey struct {
+	ID     uint `json:"id,omitempty"`
+	CardID uint `json:"card_id,omitempty"`
+	UserID uint `json:"user_id,omitempty"`
+}
+
+// where returns the key as primary key column conditions.
+func (k UndoKey) where() map[string]interface{} {
+	if k.ID != 0 {
+		return map[string]interface{}{"id": k.ID}
+	}
+	return map[string]interface{}{"card_id": k.CardID, "user_id": k.UserID}
+}
+
+// undoModels returns a new, empty model for each undoable entity.
+var undoModels = map[string]func() interface{}{
+	UndoEntityCard:         func() interface{} { return &models.Card{} },
+	UndoEntityList:         func() interface{} { return &models.BoardList{} },
+	UndoEntitySubtask:      func() interface{} { return &models.Subtask{} },
+	UndoEntityCardAssignee: func() interface{} { return &models.CardAssignee{} },
+	UndoEntityCardLabel:    func() interface{} { return &models.CardLabel{} },
+}
+
+// UndoWindow returns how long an operation can be undone, read from UNDO_WINDOW (default 2m).
+func UndoWindow() time.Duration {
+	return env.Duration("UNDO_WINDOW", 2*time.Minute)
+}
+
+// CaptureUndoSnapshot returns a JSON snapshot of a row without its associations, or an empty
+// string if the row does not exist. Take it before an operation and pass it to RecordUndo.
+func CaptureUndoSnapshot(entity string, key UndoKey) (string, error) {
+	newModel, ok := undoModels[entity]
+	if !ok {
+		return "", fmt.Errorf("unknown undo entity %q", entity)
+	}
+
+	row := newModel()
+	if err := repositories.GetRowByKey(row, key.where()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// RecordUndo records an operation the user has just performed, with the snapshot of the row
+// taken before it, and returns the token that undoes it.
+func RecordUndo(userID uint, entity, action string, key UndoKey, before string) (string, error) {
+	after, err := CaptureUndoSnapshot(entity, key)
+	if err != nil {
+		return "", err
+	}
+
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := GenerateSecureToken(24)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := repositories.DeleteExpiredUndoActions(userID, now); err != nil {
+		return "", err
+	}
+
+	undo := models.UndoAction{
+		Token:     token,
+		UserID:    userID,
+		Entity:    entity,
+		Action:    action,
+		Key:       string(keyJSON),
+		Before:    before,
+		After:     after,
+		ExpiresAt: now.Add(UndoWindow()),
+		CreatedAt: now,
+	}
+	if err := repositories.CreateUndoAction(&undo); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Undo reverses the operation recorded under a token by the given user. Created rows are
+// deleted, updated rows get their previous values back and deleted rows are restored with
+// everything deleted with them. It returns ErrUndoConflict if the row has changed since.
+func Undo(token string, userID uint, now time.Time) (*models.UndoAction, error) {
+	var undo models.UndoAction
+	if err := repositories.GetUndoActionByToken(token, &undo); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUndoNotFound
+		}
+		return nil, err
+	}
+	if undo.UserID != userID {
+		return nil, ErrUndoNotFound
+	}
+	if undo.UndoneAt != nil || now.After(undo.ExpiresAt) {
+		return nil, ErrUndoExpired
+	}
+
+	var key UndoKey
+	if err := json.Unmarshal([]byte(undo.Key), &key); err != nil {
+		return nil, err
+	}
+
+	current, err := CaptureUndoSnapshot(undo.Entity, key)
+	if err != nil {
+		return nil, err
+	}
+	if current != undo.After {
+		return nil, ErrUndoConflict
+	}
+
+	claimed, err := repositories.MarkUndoActionUndone(undo.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrUndoExpired
+	}
+	undo.UndoneAt = &now
+
+	switch undo.Action {
+	case UndoActionCreate:
+		err = undoCreate(undo.Entity, key)
+	case UndoActionUpdate:
+		err = undoUpdate(undo.Entity, undo.Before, undo.After)
+	case UndoActionDelete:
+		err = undoDelete(undo.Entity, key, undo.Before)
+	default:
+		err = fmt.Errorf("unknown undo action %q", undo.Action)
+	}
+	if err != nil {
+		// Hand the token back so the operation can still be undone once the reversal succeeds.
+		if releaseErr := repositories.ReleaseUndoActionUndone(undo.ID); releaseErr != nil {
+			log.Println("Error releasing undo token:", releaseErr)
+		}
+		if errors.Is(err, repositories.ErrParentDeleted) {
+			return nil, ErrUndoConflict
+		}
+		return nil, err
+	}
+	return &undo, nil
+}
+
+// undoCreate deletes a created row. Cards and lists go to the trash like any other deletion.
+func undoCreate(entity string, key UndoKey) error {
+	switch entity {
+	case UndoEntityCard:
+		return repositories.DeleteCard(key.ID)
+	case UndoEntityList:
+		return repositories.DeleteBoardList(key.ID)
+	}
+	return repositories.DeleteRowByKey(undoModels[entity](), key.where())
+}
+
+// undoUpdate writes back the previous values of an updated row. Undoing the completion of a
+// recurring card also removes the next occurrence it created, and undoing a move is recorded
+// in the card's list history.
+func undoUpdate(entity, before, after string) error {
+	row := undoModels[entity]()
+	if err := json.Unmarshal([]byte(before), row); err != nil {
+		return err
+	}
+	// Move the version forward rather than back, so ETags handed out before the undo go stale.
+	var current struct {
+		Version uint `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(after), &current); err != nil {
+		return err
+	}
+	if current.Version > 0 {
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"version":%d}`, current.Version+1)), row); err != nil {
+			return err
+		}
+	}
+	var previous, updated models.Card
+	if entity == UndoEntityCard {
+		if err := json.Unmarshal([]byte(before), &previous); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(after), &updated); err != nil {
+			return err
+		}
+		// Moving the card back to its previous list is a move like any other, and is held to
+		// the list's WIP limit.
+		if err := repositories.RevertCard(row.(*models.Card)); err != nil {
+			return err
+		}
+	} else if err := repositories.SaveRow(row); err != nil {
+		return err
+	}
+
+	if entity == UndoEntityCard {
+		if previous.NextOccurrenceID == nil && updated.NextOccurrenceID != nil {
+			return repositories.DeleteCard(*updated.NextOccurrenceID)
+		}
+	}
+	return nil
+}
+
+// undoDelete brings back a deleted row. Cards and lists are restored from the trash together
+// with everything deleted with them; other rows are recreated from their snapshot.
+func undoDelete(entity string, key UndoKey, before string) error {
+	switch entity {
+	case UndoEntityCard:
+		var card models.Card
+		if err := repositories.GetDeletedCardWithListByID(key.ID, &card); err != nil {
+			return err
+		}
+		return repositories.RestoreCard(&card)
+	case UndoEntityList:
+		var list models.BoardList
+		if err := repositories.GetDeletedBoardListByID(key.ID, &list); err != nil {
+			return err
+		}
+		return repositories.RestoreBoardList(&list)
+	}
+
+	row := undoModels[entity]()
+	if err := json.Unmarshal([]byte(before), row); err != nil {
+		return err
+	}
+	return repositories.CreateRow(row)
+}
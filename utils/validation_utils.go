@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"kelarin-backend/models"
+)
+
+// Length limits for user-provided text, counted in characters.
+const (
+	MaxCardTitleLength       = 255
+	MaxCardDescriptionLength = 20000
+	MaxCommentLength         = 5000
+	MaxLabelNameLength       = 100
+)
+
+// ValidateLength returns an error naming the field if value is longer than max characters.
+func ValidateLength(field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Errorf("%s must be at most %d characters", field, max)
+	}
+	return nil
+}
+
+// hexColorPattern matches "#RGB" and "#RRGGBB" hex colors.
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// IsValidHexColor reports whether color is a "#RGB" or "#RRGGBB" hex color.
+func IsValidHexColor(color string) bool {
+	return hexColorPattern.MatchString(color)
+}
+
+// IsValidPriority reports whether priority is a supported card priority.
+func IsValidPriority(priority string) bool {
+	switch priority {
+	case models.PriorityNone, models.PriorityLow, models.PriorityMedium, models.PriorityHigh, models.PriorityUrgent:
+		return true
+	}
+	return false
+}
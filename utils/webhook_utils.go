@@ -0,0 +1,472 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"kelarin-backend/env"
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// WebhookMaxAttempts is how many times a delivery is attempted before it is marked as failed.
+const WebhookMaxAttempts = 8
+
+// maxWebhookResponseBody is how much of a receiver's response is kept in the delivery log.
+const maxWebhookResponseBody = 2048
+
+// webhookBatchSize is how many due deliveries the worker sends at a time.
+const webhookBatchSize = 50
+
+// webhookWake wakes the delivery worker up when new deliveries are queued.
+var webhookWake = make(chan struct{}, 1)
+
+// WebhookPayload is the JSON body POSTed to webhooks in the "json" format.
+type WebhookPayload struct {
+	ID          string      `json:"id"`
+	Event       string      `json:"event"`
+	WorkspaceID uint        `json:"workspace_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Data        interface{} `json:"data"`
+}
+
+// IsValidWebhookEvent reports whether event is one of models.WebhookEventTypes.
+func IsValidWebhookEvent(event string) bool {
+	for _, e := range models.WebhookEventTypes {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWebhookURL checks that a webhook URL is an absolute http or https URL whose host
+// resolves to public addresses only, unless WEBHOOK_ALLOW_PRIVATE_HOSTS is set.
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(raw) > 2048 {
+		return errors.New("url must be at most 2048 characters")
+	}
+	if allowPrivateWebhookHosts() {
+		return nil
+	}
+
+	ips, err := net.LookupIP(parsed.Hostname())
+	if err != nil || len(ips) == 0 {
+		return errors.New("url host could not be resolved")
+	}
+	for _, ip := range ips {
+		if isPrivateWebhookIP(ip) {
+			return errors.New("url must not point to a private, loopback or link-local address")
+		}
+	}
+	return nil
+}
+
+// allowPrivateWebhookHosts reports whether webhooks may be sent to private addresses, which
+// WEBHOOK_ALLOW_PRIVATE_HOSTS turns on for testing with a local receiver.
+func allowPrivateWebhookHosts() bool {
+	return env.Bool("WEBHOOK_ALLOW_PRIVATE_HOSTS", false)
+}
+
+// nonPublicWebhookPrefixes are the ranges of the IANA IPv4 and IPv6 special-purpose address
+// registries that are not globally reachable, beyond those net.IP already recognises.
+var nonPublicWebhookPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// isPrivateWebhookIP reports whether an address is one webhooks must not reach: loopback,
+// private, link-local, multicast, unspecified, or in another range that is not globally
+// reachable, such as carrier-grade NAT.
+func isPrivateWebhookIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range nonPublicWebhookPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookDialControl refuses connections to private addresses. It runs on the resolved
+// address of every connection, so a host that resolves to a public address when the webhook
+// is saved and to a private one later cannot get through.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateWebhookIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// newWebhookClient returns the HTTP client deliveries are sent with. It times out after
+// WEBHOOK_TIMEOUT (default 10s), does not follow redirects and only connects to public
+// addresses unless WEBHOOK_ALLOW_PRIVATE_HOSTS is set.
+func newWebhookClient() *http.Client {
+	timeout := env.Duration("WEBHOOK_TIMEOUT", 10*time.Second)
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateWebhookHosts() {
+		dialer.Control = webhookDialControl
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// SignWebhookPayload returns the X-Kelarin-Signature of a delivery: "sha256=" followed by the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRetryDelay returns how long to wait before retrying a delivery after the given number
+// of failed attempts: WEBHOOK_RETRY_BASE (default 30s) doubled on every attempt, at most 6h.
+func WebhookRetryDelay(attempts int) time.Duration {
+	const maxDelay = 6 * time.Hour
+	delay := env.Duration("WEBHOOK_RETRY_BASE", 30*time.Second)
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// EmitWebhookEvent queues a delivery of an event to every enabled webhook of a workspace that
+// subscribes to it. Errors are logged rather than returned so that webhooks never fail the
+// action that caused the event.
+func EmitWebhookEvent(workspaceID uint, event string, data interface{}) {
+	var webhooks []models.Webhook
+	if err := repositories.GetEnabledWebhooksByWorkspace(workspaceID, &webhooks); err != nil {
+		log.Println("Error fetching webhooks:", err)
+		return
+	}
+
+	var subscribed []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	payload, err := newWebhookPayload(workspaceID, event, data)
+	if err != nil {
+		log.Println("Error building webhook payload:", err)
+		return
+	}
+
+	now := time.Now()
+	bodies := make(map[string]string)
+	deliveries := make([]models.WebhookDelivery, 0, len(subscribed))
+	for _, webhook := range subscribed {
+		body, ok := bodies[webhook.Format]
+		if !ok {
+			encoded, err := webhookBody(webhook.Format, payload)
+			if err != nil {
+				log.Printf("Error encoding %s webhook payload: %v", webhook.Format, err)
+				continue
+			}
+			body = string(encoded)
+			bodies[webhook.Format] = body
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       body,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+	if err := repositories.CreateWebhookDeliveries(deliveries); err != nil {
+		log.Println("Error queueing webhook deliveries:", err)
+		return
+	}
+
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// EmitCardWebhookEvent emits a card event to the webhooks of the card's workspace.
+func EmitCardWebhookEvent(event string, card *models.Card) {
+	workspaceID, err := repositories.GetWorkspaceIDByListID(card.ListID)
+	if err != nil {
+		log.Println("Error resolving workspace for webhook:", err)
+		return
+	}
+	EmitWebhookEvent(workspaceID, event, map[string]interface{}{"card": webhookCard(card)})
+}
+
+// EmitCardMovedWebhookEvent emits card.moved for a card that was moved out of another list.
+func EmitCardMovedWebhookEvent(card *models.Card, fromListID uint) {
+	workspaceID, err := repositories.GetWorkspaceIDByListID(card.ListID)
+	if err != nil {
+		log.Println("Error resolving workspace for webhook:", err)
+		return
+	}
+	EmitWebhookEvent(workspaceID, models.WebhookEventCardMoved, map[string]interface{}{
+		"card":         webhookCard(card),
+		"from_list_id": fromListID,
+		"to_list_id":   card.ListID,
+	})
+}
+
+// EmitMemberSharedWebhookEvent emits member.shared for a user added to a workspace.
+func EmitMemberSharedWebhookEvent(workspaceID uint, user *models.User, role string, actorID uint) {
+	EmitWebhookEvent(workspaceID, models.WebhookEventMemberShared, map[string]interface{}{
+		"user": map[string]interface{}{
+			"id":       user.ID,
+			"fullname": user.FullName,
+			"email":    user.Email,
+		},
+		"role":      role,
+		"shared_by": actorID,
+	})
+}
+
+// SendTestWebhook sends a ping event to a webhook right away, without retries, and returns
+// the logged delivery.
+func SendTestWebhook(webhook *models.Webhook) (*models.WebhookDelivery, error) {
+	payload, err := newWebhookPayload(webhook.WorkspaceID, models.WebhookEventPing, map[string]interface{}{"webhook_id": webhook.ID})
+	if err != nil {
+		return nil, err
+	}
+	body, err := webhookBody(webhook.Format, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID: webhook.ID,
+		EventID:   payload.ID,
+		Event:     models.WebhookEventPing,
+		Payload:   string(body),
+		Status:    models.WebhookDeliveryPending,
+	}
+	if err := repositories.CreateWebhookDelivery(&delivery); err != nil {
+		return nil, err
+	}
+	if err := attemptWebhookDelivery(&delivery, webhook, 1); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// StartWebhookDeliveryWorker sends queued webhook deliveries in the background. It wakes up
+// when new deliveries are queued and every WEBHOOK_POLL_INTERVAL (default 10s) for retries,
+// and deletes finished deliveries older than WEBHOOK_LOG_RETENTION (default 720h, 30 days).
+func StartWebhookDeliveryWorker() {
+	interval := env.Duration("WEBHOOK_POLL_INTERVAL", 10*time.Second)
+	retention := env.Duration("WEBHOOK_LOG_RETENTION", 30*24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var lastPurge time.Time
+		for {
+			now := time.Now()
+			deliverDueWebhooks(now)
+			if now.Sub(lastPurge) >= time.Hour {
+				if _, err := repositories.DeleteWebhookDeliveriesBefore(now.Add(-retention)); err != nil {
+					log.Println("Error purging webhook deliveries:", err)
+				}
+				lastPurge = now
+			}
+
+			select {
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+		}
+	}()
+
+	log.Printf("Webhook delivery worker started (interval %s, retention %s)", interval, retention)
+}
+
+// deliverDueWebhooks attempts every pending delivery whose next attempt is due. Each batch is
+// claimed for long enough to send it, so several workers never send the same delivery.
+func deliverDueWebhooks(now time.Time) {
+	for {
+		claimUntil := time.Now().Add(webhookBatchSize * env.Duration("WEBHOOK_TIMEOUT", 10*time.Second))
+		var deliveries []models.WebhookDelivery
+		if err := repositories.ClaimDueWebhookDeliveries(now, claimUntil, webhookBatchSize, &deliveries); err != nil {
+			log.Println("Error fetching webhook deliveries:", err)
+			return
+		}
+
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			if !delivery.Webhook.Enabled {
+				delivery.Status = models.WebhookDeliveryFailed
+				delivery.NextAttemptAt = nil
+				delivery.Error = "Webhook is disabled"
+				if err := repositories.UpdateWebhookDelivery(delivery); err != nil {
+					log.Println("Error updating webhook delivery:", err)
+				}
+				continue
+			}
+			if err := attemptWebhookDelivery(delivery, &delivery.Webhook, WebhookMaxAttempts); err != nil {
+				log.Println("Error updating webhook delivery:", err)
+			}
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attemptWebhookDelivery POSTs a delivery to its webhook and records the outcome. Only a
+// failure to record the outcome is returned.
+func attemptWebhookDelivery(delivery *models.WebhookDelivery, webhook *models.Webhook, maxAttempts int) error {
+	sendWebhookAttempt(delivery, webhook, maxAttempts, time.Now())
+	return repositories.UpdateWebhookDelivery(delivery)
+}
+
+// sendWebhookAttempt POSTs a delivery to its webhook and sets the outcome on the delivery. A
+// delivery succeeds on any 2xx response; otherwise it is retried with exponential backoff
+// until it has been attempted maxAttempts times.
+func sendWebhookAttempt(delivery *models.WebhookDelivery, webhook *models.Webhook, maxAttempts int, now time.Time) {
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+
+	err := postWebhook(delivery, webhook, now)
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.Error = ""
+	} else {
+		delivery.Error = err.Error()
+		if delivery.Attempts >= maxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(WebhookRetryDelay(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+}
+
+// postWebhook sends a delivery's payload with its signature headers and keeps the start of
+// the response in the delivery.
+func postWebhook(delivery *models.WebhookDelivery, webhook *models.Webhook, now time.Time) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KelarIn-Webhooks/1.0")
+	req.Header.Set("X-Kelarin-Event", delivery.Event)
+	req.Header.Set("X-Kelarin-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Kelarin-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Kelarin-Signature", SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := newWebhookClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	delivery.ResponseStatus = resp.StatusCode
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody)); err == nil {
+		delivery.ResponseBody = string(data)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newWebhookPayload wraps event data in a payload with a new event ID.
+func newWebhookPayload(workspaceID uint, event string, data interface{}) (*WebhookPayload, error) {
+	id, err := GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookPayload{
+		ID:          id,
+		Event:       event,
+		WorkspaceID: workspaceID,
+		CreatedAt:   time.Now(),
+		Data:        data,
+	}, nil
+}
+
+// webhookCard returns the fields of a card that are sent to webhooks. Associations are left
+// out so that no user details beyond IDs leave the workspace.
+func webhookCard(card *models.Card) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           card.ID,
+		"title":        card.Title,
+		"description":  card.Description,
+		"list_id":      card.ListID,
+		"priority":     card.Priority,
+		"start_date":   card.StartDate,
+		"deadline":     card.Deadline,
+		"completed_at": card.CompletedAt,
+		"archived_at":  card.ArchivedAt,
+		"version":      card.Version,
+		"created_at":   card.CreatedAt,
+		"updated_at":   card.UpdatedAt,
+	}
+}
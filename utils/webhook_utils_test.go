@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kelarin-backend/models"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"card.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("secret", 1700000000, body); got != want {
+		t.Errorf("SignWebhookPayload() = %q, want %q", got, want)
+	}
+	if got := SignWebhookPayload("secret", 1700000001, body); got == want {
+		t.Error("SignWebhookPayload() does not depend on the timestamp")
+	}
+	if got := SignWebhookPayload("other", 1700000000, body); got == want {
+		t.Error("SignWebhookPayload() does not depend on the secret")
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE", "30s")
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, 64 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := WebhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("WebhookRetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, raw := range []string{"ftp://example.com/hook", "/hook", "http://127.0.0.1/hook", "http://10.0.0.5/hook", "http://[::1]/hook", "http://169.254.169.254/latest", "http://100.100.100.200/latest", "http://192.0.0.8/hook", "http://[::ffff:10.0.0.5]/hook"} {
+		if err := ValidateWebhookURL(raw); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) = nil, want an error", raw)
+		}
+	}
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+	if err := ValidateWebhookURL("http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("ValidateWebhookURL() with private hosts allowed = %v, want nil", err)
+	}
+}
+
+// newTestDelivery returns a pending delivery and the webhook it is sent to.
+func newTestDelivery(url string) (*models.WebhookDelivery, *models.Webhook) {
+	webhook := &models.Webhook{ID: 3, URL: url, Secret: "secret", Format: models.WebhookFormatJSON, Enabled: true}
+	delivery := &models.WebhookDelivery{
+		ID:        7,
+		WebhookID: webhook.ID,
+		Event:     models.WebhookEventCardCreated,
+		Payload:   `{"event":"card.created"}`,
+		Status:    models.WebhookDeliveryPending,
+	}
+	return delivery, webhook
+}
+
+func TestSendWebhookAttemptSucceeds(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get("X-Kelarin-Timestamp"), 10, 64)
+		switch {
+		case r.Method != http.MethodPost:
+			t.Errorf("method = %s, want POST", r.Method)
+		case r.Header.Get("Content-Type") != "application/json":
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		case r.Header.Get("X-Kelarin-Event") != models.WebhookEventCardCreated:
+			t.Errorf("X-Kelarin-Event = %q", r.Header.Get("X-Kelarin-Event"))
+		case r.Header.Get("X-Kelarin-Delivery") != "7":
+			t.Errorf("X-Kelarin-Delivery = %q", r.Header.Get("X-Kelarin-Delivery"))
+		case err != nil:
+			t.Errorf("X-Kelarin-Timestamp = %q", r.Header.Get("X-Kelarin-Timestamp"))
+		case r.Header.Get("X-Kelarin-Signature") != SignWebhookPayload("secret", timestamp, body):
+			t.Errorf("X-Kelarin-Signature = %q does not match the body", r.Header.Get("X-Kelarin-Signature"))
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	delivery, webhook := newTestDelivery(server.URL)
+	sendWebhookAttempt(delivery, webhook, WebhookMaxAttempts, time.Now())
+
+	if delivery.Status != models.WebhookDeliverySucceeded {
+		t.Fatalf("Status = %q, want %q (error %q)", delivery.Status, models.WebhookDeliverySucceeded, delivery.Error)
+	}
+	if delivery.Attempts != 1 || delivery.DeliveredAt == nil || delivery.NextAttemptAt != nil {
+		t.Errorf("Attempts = %d, DeliveredAt = %v, NextAttemptAt = %v", delivery.Attempts, delivery.DeliveredAt, delivery.NextAttemptAt)
+	}
+	if delivery.ResponseStatus != http.StatusOK || delivery.ResponseBody != "ok" {
+		t.Errorf("response = %d %q, want 200 \"ok\"", delivery.ResponseStatus, delivery.ResponseBody)
+	}
+}
+
+func TestSendWebhookAttemptRetriesServerErrors(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+	t.Setenv("WEBHOOK_RETRY_BASE", "30s")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	delivery, webhook := newTestDelivery(server.URL)
+	now := time.Now()
+	for attempt, delay := range []time.Duration{30 * time.Second, time.Minute} {
+		sendWebhookAttempt(delivery, webhook, 3, now)
+		if delivery.Status != models.WebhookDeliveryPending {
+			t.Fatalf("attempt %d: Status = %q, want pending", attempt+1, delivery.Status)
+		}
+		if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(now.Add(delay)) {
+			t.Errorf("attempt %d: NextAttemptAt = %v, want %v", attempt+1, delivery.NextAttemptAt, now.Add(delay))
+		}
+		if delivery.ResponseStatus != http.StatusServiceUnavailable || !strings.Contains(delivery.Error, "503") {
+			t.Errorf("attempt %d: ResponseStatus = %d, Error = %q", attempt+1, delivery.ResponseStatus, delivery.Error)
+		}
+	}
+
+	sendWebhookAttempt(delivery, webhook, 3, now)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.NextAttemptAt != nil || delivery.Attempts != 3 {
+		t.Errorf("after the last attempt: Status = %q, NextAttemptAt = %v, Attempts = %d", delivery.Status, delivery.NextAttemptAt, delivery.Attempts)
+	}
+}
+
+func TestSendWebhookAttemptTimesOut(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+	t.Setenv("WEBHOOK_TIMEOUT", "50ms")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // The request is only canceled once its body has been read
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	delivery, webhook := newTestDelivery(server.URL)
+	now := time.Now()
+	sendWebhookAttempt(delivery, webhook, WebhookMaxAttempts, now)
+
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Error == "" || delivery.ResponseStatus != 0 {
+		t.Errorf("Status = %q, Error = %q, ResponseStatus = %d", delivery.Status, delivery.Error, delivery.ResponseStatus)
+	}
+	if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.After(now) {
+		t.Errorf("NextAttemptAt = %v, want a retry after %v", delivery.NextAttemptAt, now)
+	}
+}
+
+func TestSendWebhookAttemptRefusesPrivateHosts(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	delivery, webhook := newTestDelivery(server.URL)
+	sendWebhookAttempt(delivery, webhook, WebhookMaxAttempts, time.Now())
+
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("delivery reached a loopback receiver")
+	}
+	if delivery.Status != models.WebhookDeliveryPending || !strings.Contains(delivery.Error, "not allowed") {
+		t.Errorf("Status = %q, Error = %q", delivery.Status, delivery.Error)
+	}
+}
+
+func TestSendWebhookAttemptDoesNotFollowRedirects(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "true")
+
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	delivery, webhook := newTestDelivery(server.URL)
+	sendWebhookAttempt(delivery, webhook, WebhookMaxAttempts, time.Now())
+
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("delivery followed the redirect")
+	}
+	if delivery.Status != models.WebhookDeliveryPending || delivery.ResponseStatus != http.StatusTemporaryRedirect {
+		t.Errorf("Status = %q, ResponseStatus = %d", delivery.Status, delivery.ResponseStatus)
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"kelarin-backend/models"
+	"kelarin-backend/repositories"
+)
+
+// ErrWIPLimitReached is returned when adding cards to a list would exceed its hard WIP limit.
+var ErrWIPLimitReached = repositories.ErrWIPLimitReached
+
+// IsValidWIPLimitMode reports whether mode is a supported WIP limit mode.
+func IsValidWIPLimitMode(mode string) bool {
+	return mode == models.WIPLimitHard || mode == models.WIPLimitSoft
+}
+
+// WIPCapacity returns how many more cards a list can take before it goes over its WIP limit,
+// or -1 if the list has no limit.
+func WIPCapacity(list *models.BoardList) (int, error) {
+	if list.WIPLimit <= 0 {
+		return -1, nil
+	}
+	count, err := repositories.CountCardsInList(list.ID)
+	if err != nil {
+		return 0, err
+	}
+	if remaining := list.WIPLimit - int(count); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// CheckWIPLimit checks that adding cards to a list keeps it within its WIP limit. Going over a
+// hard limit returns an error wrapping ErrWIPLimitReached; going over a soft limit returns a
+// warning to show with the result.
+func CheckWIPLimit(listID uint, adding int) (string, error) {
+	var list models.BoardList
+	if err := repositories.GetBoardListByID(listID, &list); err != nil {
+		return "", err
+	}
+	remaining, err := WIPCapacity(&list)
+	if err != nil || remaining < 0 || adding <= remaining {
+		return "", err
+	}
+	if list.WIPLimitMode == models.WIPLimitHard {
+		return "", WIPLimitError(&list)
+	}
+	return WIPLimitWarning(&list), nil
+}
+
+// WIPLimitError describes a list that cannot take more cards under its hard WIP limit.
+func WIPLimitError(list *models.BoardList) error {
+	return repositories.WIPLimitError(list)
+}
+
+// WIPLimitWarning describes a list that is over its soft WIP limit.
+func WIPLimitWarning(list *models.BoardList) string {
+	return fmt.Sprintf("%q is over its WIP limit of %d cards", list.Title, list.WIPLimit)
+}
+
+// JoinWarnings joins the non-empty warnings of one change into a single message.
+func JoinWarnings(warnings ...string) string {
+	var kept []string
+	for _, warning := range warnings {
+		if warning != "" {
+			kept = append(kept, warning)
+		}
+	}
+	return strings.Join(kept, "; ")
+}